@@ -11,35 +11,54 @@ package main
 import (
 	"context"
 	"flag"
-	"log"
 	"github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
-	record "github.com/libp2p/go-libp2p-record"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
 	"github.com/multiformats/go-multiaddr"
+	"log"
+	"orcanet/config"
+	"orcanet/store"
 	"orcanet/util"
 	"orcanet/validator"
-	"github.com/libp2p/go-libp2p/p2p/protocol/circuitv2/relay"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	var bootstrapPeer string
-	flag.StringVar(&bootstrapPeer, "bootstrap", "", "Specify a bootstrap peer multiaddr")
+	//Bootstrap nodes serve the DHT, have no gRPC server or registry and only connect to the peers they are given
+	defaults := config.Default()
+	defaults.GRPCPort = 0
+	defaults.Bootstrap.File = ""
+	defaults.DHT.Mode = "server"
+	defaults.RegistryPath = ""
+	settings := config.Bind(flag.CommandLine, defaults)
 	flag.Parse()
 
 	ctx := context.Background()
 
-	privKey, err := util.CheckOrCreatePrivateKey("privateKey.pem");
-	if(err != nil){
-		panic(err);
+	cfg, err := settings.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	keyType, err := util.ParseKeyType(cfg.KeyType)
+	if err != nil {
+		panic(err)
+	}
+	privKey, err := util.CheckOrCreatePrivateKey(cfg.KeyPath, keyType)
+	if err != nil {
+		panic(err)
 	}
 
-	//Construct multiaddr from string and create host to listen on it. Will listen on all interfaces
-	sourceMultiAddr, _ := multiaddr.NewMultiaddr("/ip4/0.0.0.0/tcp/44981")
+	//Create host to listen on the configured multiaddrs. By default it listens on all interfaces
 	opts := []libp2p.Option{
-		libp2p.ListenAddrStrings(sourceMultiAddr.String()),
-		libp2p.Identity(privKey), //derive id from private key
+		libp2p.ListenAddrStrings(cfg.ListenAddrs...),
+		libp2p.Identity(privKey),  //derive id from private key
+		libp2p.EnableNATService(), //dial back market servers so they can tell whether they are reachable
 	}
 	host, err := libp2p.New(opts...)
 	if err != nil {
@@ -62,14 +81,26 @@ func main() {
 	// client because we want each peer to maintain its own local copy of the
 	// DHT, so that the bootstrapping node of the DHT can go down without
 	// inhibiting future peer discovery.
-	var validator record.Validator = validator.OrcaValidator{}
+	// The records are kept on disk so they are still served after a restart.
+	dhtMode, err := config.ParseDHTMode(cfg.DHT.Mode)
+	if err != nil {
+		panic(err)
+	}
+	namespaces := validator.NewOrcaNamespaces()
+	datastore, err := store.Open(cfg.Datastore.Kind, cfg.Datastore.Path)
+	if err != nil {
+		panic(err)
+	}
 	var options []dht.Option
-	options = append(options, dht.Mode(dht.ModeServer))
-	options = append(options, dht.ProtocolPrefix("orcanet/market"), dht.Validator(validator))
+	options = append(options, dht.Mode(dhtMode))
+	options = append(options, dht.ProtocolPrefix(protocol.ID(cfg.DHT.ProtocolPrefix)), dht.Validator(namespaces))
+	options = append(options, dht.Datastore(datastore), dht.MaxRecordAge(store.MaxRecordAge))
 	kDHT, err := dht.New(ctx, host, options...)
 	if err != nil {
 		panic(err)
 	}
+	collector := store.NewCollector(datastore, namespaces, cfg.Datastore.GCInterval, store.MaxRecordAge)
+	collector.Start(ctx)
 
 	// Bootstrap the DHT. In the default configuration, this spawns a Background
 	// thread that will refresh the peer table every five minutes.
@@ -78,29 +109,38 @@ func main() {
 		panic(err)
 	}
 
-	if bootstrapPeer != "" {
-		connectToBootstrapPeer(bootstrapPeer, host, ctx)
-	} 
-	go util.DiscoverPeers(ctx, host, kDHT, "orcanet/market")
+	bootstrapPeers, err := cfg.BootstrapPeers()
+	if err != nil {
+		panic(err)
+	}
+	for _, peerAddr := range bootstrapPeers {
+		connectToBootstrapPeer(peerAddr, host, ctx)
+	}
+	//nodes of a network find each other under its protocol prefix, so separate networks stay apart
+	go util.DiscoverPeers(ctx, host, kDHT, cfg.DHT.ProtocolPrefix)
 
-	select {}
+	// Run until interrupted, then close the datastore so nothing is lost
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+	log.Println("Shutting down")
+	collector.Stop()
+	kDHT.Close()
+	host.Close()
+	datastore.Close()
 }
 
 /*
  *
- * Connect to a bootstrap peer using the specified multiaddr string.
+ * Connect to a bootstrap peer using the specified multiaddr.
  *
  * Parameters:
- *   multiAddr: Multiaddr string of the peer you are trying to connect to.
+ *   peerAddr: Multiaddr of the peer you are trying to connect to.
  *   host: libp2p host
  *   ctx: the context
- * 
+ *
  */
-func connectToBootstrapPeer(multiAddr string, host host.Host, ctx context.Context){
-	peerAddr, err := multiaddr.NewMultiaddr(multiAddr)
-	if err != nil {
-		panic(err)
-	}
+func connectToBootstrapPeer(peerAddr multiaddr.Multiaddr, host host.Host, ctx context.Context) {
 	peerinfo, _ := peer.AddrInfoFromP2pAddr(peerAddr)
 	go func() {
 		if err := host.Connect(ctx, *peerinfo); err != nil {
@@ -109,4 +149,4 @@ func connectToBootstrapPeer(multiAddr string, host host.Host, ctx context.Contex
 			log.Println("Connection established with bootstrap node:", *peerinfo)
 		}
 	}()
-}
\ No newline at end of file
+}
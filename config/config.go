@@ -0,0 +1,501 @@
+/*
+ * Settings shared by the market server and the bootstrap node. Each setting is read from,
+ * in increasing order of precedence, the node's defaults, a YAML config file, environment
+ * variables and command line flags, so several nodes can run on one host from the same build.
+ */
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"gopkg.in/yaml.v3"
+	"orcanet/market"
+	"orcanet/store"
+	"orcanet/util"
+)
+
+// Environment variable holding the path of the config file, if -config is not given.
+const ConfigEnv = "ORCANET_CONFIG"
+
+// The settings of a node. Field names in the config file are given by the yaml tags.
+type Config struct {
+	// Multiaddrs the libp2p host listens on
+	ListenAddrs []string `yaml:"listenAddrs"`
+	// Port the gRPC server listens on, 0 for nodes without one
+	GRPCPort int `yaml:"grpcPort"`
+	// PEM file holding the node's private key, generated if it does not exist
+	KeyPath string `yaml:"keyPath"`
+	// Type of the key generated if KeyPath does not exist: rsa, ed25519 or secp256k1
+	KeyType   string    `yaml:"keyType"`
+	Bootstrap Bootstrap `yaml:"bootstrap"`
+	DHT       DHT       `yaml:"dht"`
+	Datastore Datastore `yaml:"datastore"`
+	// Directory of the database keeping the files the server registered, empty for nodes
+	// without a gRPC server
+	RegistryPath string `yaml:"registryPath"`
+	Market       Market `yaml:"market"`
+}
+
+// Where a node finds the peers it connects to on startup. Peers from both are used.
+type Bootstrap struct {
+	// Multiaddrs of bootstrap peers, each ending in /p2p/<peer ID>
+	Peers []string `yaml:"peers"`
+	// File listing bootstrap peers, one multiaddr per line, or empty to read none
+	File string `yaml:"file"`
+}
+
+// How a node takes part in the DHT.
+type DHT struct {
+	// client, server or auto, which switches between them as AutoNAT finds the host
+	// reachable or not
+	Mode string `yaml:"mode"`
+	// Prefix of the DHT protocol IDs, nodes only talk to nodes using the same prefix. Nodes
+	// also advertise themselves to each other and announce registrations under it.
+	ProtocolPrefix string `yaml:"protocolPrefix"`
+}
+
+// How the market server registers and looks up files. Nodes without a gRPC server
+// ignore these.
+type Market struct {
+	// Where registrations are stored: chain or provider, see market.ParseStorageMode
+	Storage string `yaml:"storage"`
+	// How often registered files are re-put on the DHT, e.g. 6h
+	RepublishInterval time.Duration `yaml:"republishInterval"`
+	// How many files of a batch RPC are worked on at once
+	BatchParallelism int `yaml:"batchParallelism"`
+	// How often WatchHolders looks up a file for changes
+	WatchInterval time.Duration `yaml:"watchInterval"`
+	Probe         Probe         `yaml:"probe"`
+}
+
+// How the market server checks whether holders are reachable.
+type Probe struct {
+	// How long to wait for a holder to answer
+	Timeout time.Duration `yaml:"timeout"`
+	// How long results are reused for, 0 to probe every time
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	// Also dial loopback, private and link-local addresses, for networks run on a LAN
+	Private bool `yaml:"private"`
+}
+
+// Where a node keeps the records it stores for the network.
+type Datastore struct {
+	// memory, leveldb or badger
+	Kind string `yaml:"kind"`
+	// Directory of a leveldb or badger datastore
+	Path string `yaml:"path"`
+	// How often expired records are removed, e.g. 1h
+	GCInterval time.Duration `yaml:"gcInterval"`
+}
+
+/*
+ * The defaults of the market server. Other nodes start from these and change what they
+ * need to.
+ *
+ * Returns:
+ *   The default settings
+ */
+func Default() Config {
+	return Config{
+		ListenAddrs: []string{"/ip4/0.0.0.0/tcp/44981"},
+		GRPCPort:    50051,
+		KeyPath:     "privateKey.pem",
+		KeyType:     "ed25519",
+		Bootstrap:   Bootstrap{File: "bootstrap.peers"},
+		DHT:         DHT{Mode: "auto", ProtocolPrefix: "orcanet/market"},
+		Datastore: Datastore{
+			Kind:       store.LevelDB,
+			Path:       "datastore",
+			GCInterval: store.DefaultCollectInterval,
+		},
+		RegistryPath: "registry",
+		Market: Market{
+			Storage:           "chain",
+			RepublishInterval: market.DefaultRepublishInterval,
+			BatchParallelism:  market.DefaultBatchParallelism,
+			WatchInterval:     market.DefaultWatchInterval,
+			Probe: Probe{
+				Timeout:  market.DefaultProbeTimeout,
+				CacheTTL: market.DefaultProbeCacheTTL,
+			},
+		},
+	}
+}
+
+// A setting that can be overridden with an environment variable and a flag.
+type override struct {
+	env  string
+	flag string
+	// Applies the value of the environment variable or flag to a config
+	apply func(c *Config, value string) error
+}
+
+var overrides = []override{
+	{"ORCANET_LISTEN", "listen", func(c *Config, value string) error {
+		c.ListenAddrs = splitList(value)
+		return nil
+	}},
+	{"ORCANET_GRPC_PORT", "port", func(c *Config, value string) error {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid gRPC port %q", value)
+		}
+		c.GRPCPort = port
+		return nil
+	}},
+	{"ORCANET_KEY", "key", func(c *Config, value string) error {
+		c.KeyPath = value
+		return nil
+	}},
+	{"ORCANET_KEY_TYPE", "keytype", func(c *Config, value string) error {
+		c.KeyType = value
+		return nil
+	}},
+	{"ORCANET_BOOTSTRAP", "bootstrap", func(c *Config, value string) error {
+		c.Bootstrap.Peers = splitList(value)
+		return nil
+	}},
+	{"ORCANET_BOOTSTRAP_FILE", "bootstrap-file", func(c *Config, value string) error {
+		c.Bootstrap.File = value
+		return nil
+	}},
+	{"ORCANET_DHT_MODE", "dht-mode", func(c *Config, value string) error {
+		c.DHT.Mode = value
+		return nil
+	}},
+	{"ORCANET_DHT_PREFIX", "dht-prefix", func(c *Config, value string) error {
+		c.DHT.ProtocolPrefix = value
+		return nil
+	}},
+	{"ORCANET_DATASTORE", "datastore", func(c *Config, value string) error {
+		c.Datastore.Kind = value
+		return nil
+	}},
+	{"ORCANET_DATASTORE_PATH", "datastore-path", func(c *Config, value string) error {
+		c.Datastore.Path = value
+		return nil
+	}},
+	{"ORCANET_DATASTORE_GC", "datastore-gc", func(c *Config, value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid datastore GC interval %q", value)
+		}
+		c.Datastore.GCInterval = interval
+		return nil
+	}},
+	{"ORCANET_REGISTRY", "registry", func(c *Config, value string) error {
+		c.RegistryPath = value
+		return nil
+	}},
+	{"ORCANET_STORAGE", "storage", func(c *Config, value string) error {
+		c.Market.Storage = value
+		return nil
+	}},
+	{"ORCANET_REPUBLISH", "republish", func(c *Config, value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid republish interval %q", value)
+		}
+		c.Market.RepublishInterval = interval
+		return nil
+	}},
+	{"ORCANET_BATCH_PARALLELISM", "batch-parallelism", func(c *Config, value string) error {
+		parallelism, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid batch parallelism %q", value)
+		}
+		c.Market.BatchParallelism = parallelism
+		return nil
+	}},
+	{"ORCANET_WATCH_INTERVAL", "watch-interval", func(c *Config, value string) error {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid watch interval %q", value)
+		}
+		c.Market.WatchInterval = interval
+		return nil
+	}},
+	{"ORCANET_PROBE_TIMEOUT", "probe-timeout", func(c *Config, value string) error {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid probe timeout %q", value)
+		}
+		c.Market.Probe.Timeout = timeout
+		return nil
+	}},
+	{"ORCANET_PROBE_CACHE", "probe-cache", func(c *Config, value string) error {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid probe cache TTL %q", value)
+		}
+		c.Market.Probe.CacheTTL = ttl
+		return nil
+	}},
+	{"ORCANET_PROBE_PRIVATE", "probe-private", func(c *Config, value string) error {
+		private, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid probe-private value %q", value)
+		}
+		c.Market.Probe.Private = private
+		return nil
+	}},
+}
+
+// Reads the settings of a node once its flags are parsed.
+type Loader struct {
+	flags    *flag.FlagSet
+	defaults Config
+	path     *string
+}
+
+/*
+ * Define the flags of the config on a flag set: -config and one flag per setting. Lists
+ * are given as comma separated values.
+ *
+ * Parameters:
+ *   flags: The flag set, usually flag.CommandLine
+ *   defaults: The node's defaults, shown in the usage message. Nodes without a gRPC
+ *             server set GRPCPort to 0 and RegistryPath to "", and get no -port,
+ *             -registry or market flags.
+ *
+ * Returns:
+ *   A loader to read the config with once the flags are parsed
+ */
+func Bind(flags *flag.FlagSet, defaults Config) *Loader {
+	l := &Loader{flags: flags, defaults: defaults}
+	l.path = flags.String("config", "", "Path of a YAML config file, also read from $"+ConfigEnv)
+	flags.String("listen", strings.Join(defaults.ListenAddrs, ","), "Comma separated multiaddrs the libp2p host listens on ($ORCANET_LISTEN)")
+	if defaults.GRPCPort != 0 {
+		flags.Int("port", defaults.GRPCPort, "The gRPC server port ($ORCANET_GRPC_PORT)")
+	}
+	flags.String("key", defaults.KeyPath, "Path of the private key file, generated if it does not exist ($ORCANET_KEY)")
+	flags.String("keytype", defaults.KeyType, "Key type to generate if the key file does not exist: rsa, ed25519 or secp256k1 ($ORCANET_KEY_TYPE)")
+	flags.String("bootstrap", strings.Join(defaults.Bootstrap.Peers, ","), "Comma separated multiaddrs of bootstrap peers ($ORCANET_BOOTSTRAP)")
+	flags.String("bootstrap-file", defaults.Bootstrap.File, "File listing bootstrap peers, one multiaddr per line, empty for none ($ORCANET_BOOTSTRAP_FILE)")
+	flags.String("dht-mode", defaults.DHT.Mode, "DHT mode: client, server or auto ($ORCANET_DHT_MODE)")
+	flags.String("dht-prefix", defaults.DHT.ProtocolPrefix, "Prefix of the DHT protocol IDs ($ORCANET_DHT_PREFIX)")
+	flags.String("datastore", defaults.Datastore.Kind, "Where the DHT keeps the records it stores for the network: memory, leveldb or badger ($ORCANET_DATASTORE)")
+	flags.String("datastore-path", defaults.Datastore.Path, "Directory of the leveldb or badger datastore ($ORCANET_DATASTORE_PATH)")
+	flags.Duration("datastore-gc", defaults.Datastore.GCInterval, "How often expired records are removed from the datastore ($ORCANET_DATASTORE_GC)")
+	if defaults.RegistryPath != "" {
+		flags.String("registry", defaults.RegistryPath, "Directory of the database that keeps the files this server registered across restarts ($ORCANET_REGISTRY)")
+	}
+	if defaults.GRPCPort != 0 {
+		flags.String("storage", defaults.Market.Storage, "Where registrations are stored: chain (one value per file) or provider (DHT provider records) ($ORCANET_STORAGE)")
+		flags.Duration("republish", defaults.Market.RepublishInterval, "How often registered files are re-put on the DHT ($ORCANET_REPUBLISH)")
+		flags.Int("batch-parallelism", defaults.Market.BatchParallelism, "How many files of a batch RPC are worked on at once ($ORCANET_BATCH_PARALLELISM)")
+		flags.Duration("watch-interval", defaults.Market.WatchInterval, "How often WatchHolders looks up a file for changes ($ORCANET_WATCH_INTERVAL)")
+		flags.Duration("probe-timeout", defaults.Market.Probe.Timeout, "How long to wait for a holder to answer a liveness probe ($ORCANET_PROBE_TIMEOUT)")
+		flags.Duration("probe-cache", defaults.Market.Probe.CacheTTL, "How long liveness probe results are reused for ($ORCANET_PROBE_CACHE)")
+		flags.Bool("probe-private", defaults.Market.Probe.Private, "Let liveness probes dial loopback, private and link-local addresses, for networks run on a LAN ($ORCANET_PROBE_PRIVATE)")
+	}
+	return l
+}
+
+/*
+ * Read the config: the defaults, overridden by the config file if one is given, then by
+ * environment variables, then by the flags set on the command line.
+ *
+ * Returns:
+ *   The config
+ *   An error, if the file could not be read or a setting is invalid
+ */
+func (l *Loader) Load() (Config, error) {
+	c := l.defaults
+	c.ListenAddrs = append([]string(nil), l.defaults.ListenAddrs...)
+	c.Bootstrap.Peers = append([]string(nil), l.defaults.Bootstrap.Peers...)
+
+	path := *l.path
+	if path == "" {
+		path = os.Getenv(ConfigEnv)
+	}
+	if path != "" {
+		if err := readFile(path, &c); err != nil {
+			return c, err
+		}
+	}
+
+	for _, o := range overrides {
+		if value, ok := os.LookupEnv(o.env); ok {
+			if err := o.apply(&c, value); err != nil {
+				return c, fmt.Errorf("%s: %w", o.env, err)
+			}
+		}
+	}
+
+	var err error
+	l.flags.Visit(func(f *flag.Flag) {
+		for _, o := range overrides {
+			if o.flag == f.Name && err == nil {
+				if applyErr := o.apply(&c, f.Value.String()); applyErr != nil {
+					err = fmt.Errorf("-%s: %w", o.flag, applyErr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return c, err
+	}
+	if l.defaults.RegistryPath != "" && c.RegistryPath == "" {
+		return c, errors.New("registryPath: a registry path is required")
+	}
+	return c, c.Validate()
+}
+
+// Decode a YAML config file over c, rejecting fields that do not exist.
+func readFile(path string, c *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Split a comma separated list, dropping empty items.
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+/*
+ * Check that every setting can be used.
+ *
+ * Returns:
+ *   An error naming the first invalid setting, if any
+ */
+func (c Config) Validate() error {
+	if len(c.ListenAddrs) == 0 {
+		return errors.New("listenAddrs: at least one listen address is required")
+	}
+	for _, addr := range c.ListenAddrs {
+		if _, err := multiaddr.NewMultiaddr(addr); err != nil {
+			return fmt.Errorf("listenAddrs: %q: %w", addr, err)
+		}
+	}
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+		return fmt.Errorf("grpcPort: %d is out of range", c.GRPCPort)
+	}
+	if c.KeyPath == "" {
+		return errors.New("keyPath: a key path is required")
+	}
+	if _, err := util.ParseKeyType(c.KeyType); err != nil {
+		return fmt.Errorf("keyType: %w", err)
+	}
+	for _, addr := range c.Bootstrap.Peers {
+		if _, err := peer.AddrInfoFromString(addr); err != nil {
+			return fmt.Errorf("bootstrap.peers: %q: %w", addr, err)
+		}
+	}
+	if _, err := ParseDHTMode(c.DHT.Mode); err != nil {
+		return fmt.Errorf("dht.mode: %w", err)
+	}
+	if c.DHT.ProtocolPrefix == "" {
+		return errors.New("dht.protocolPrefix: a protocol prefix is required")
+	}
+	switch c.Datastore.Kind {
+	case store.Memory:
+	case store.LevelDB, store.Badger:
+		if c.Datastore.Path == "" {
+			return errors.New("datastore.path: a path is required for an on-disk datastore")
+		}
+	default:
+		return fmt.Errorf("datastore.kind: unknown datastore %q, expected %s, %s or %s", c.Datastore.Kind, store.Memory, store.LevelDB, store.Badger)
+	}
+	if c.Datastore.GCInterval <= 0 {
+		return fmt.Errorf("datastore.gcInterval: %s is not a positive interval", c.Datastore.GCInterval)
+	}
+	return c.Market.Validate()
+}
+
+/*
+ * Check that every market server setting can be used.
+ *
+ * Returns:
+ *   An error naming the first invalid setting, if any
+ */
+func (m Market) Validate() error {
+	if _, err := market.ParseStorageMode(m.Storage); err != nil {
+		return fmt.Errorf("market.storage: %w", err)
+	}
+	if m.RepublishInterval <= 0 {
+		return fmt.Errorf("market.republishInterval: %s is not a positive interval", m.RepublishInterval)
+	}
+	if m.BatchParallelism <= 0 {
+		return fmt.Errorf("market.batchParallelism: %d is not a positive limit", m.BatchParallelism)
+	}
+	if m.WatchInterval <= 0 {
+		return fmt.Errorf("market.watchInterval: %s is not a positive interval", m.WatchInterval)
+	}
+	if m.Probe.Timeout <= 0 {
+		return fmt.Errorf("market.probe.timeout: %s is not a positive timeout", m.Probe.Timeout)
+	}
+	if m.Probe.CacheTTL < 0 {
+		return fmt.Errorf("market.probe.cacheTTL: %s is negative", m.Probe.CacheTTL)
+	}
+	return nil
+}
+
+/*
+ * Parse the name of a DHT mode.
+ *
+ * Parameters:
+ *   name: "client", "server" or "auto"
+ *
+ * Returns:
+ *   The mode, to be passed to dht.Mode
+ *   An error, if the name is not known
+ */
+func ParseDHTMode(name string) (dht.ModeOpt, error) {
+	switch name {
+	case "client":
+		return dht.ModeClient, nil
+	case "server":
+		return dht.ModeServer, nil
+	case "auto":
+		return dht.ModeAuto, nil
+	}
+	return dht.ModeClient, fmt.Errorf("unknown DHT mode %q, expected client, server or auto", name)
+}
+
+/*
+ * Collect the bootstrap peers from the config and from the bootstrap file, if any.
+ *
+ * Returns:
+ *   The multiaddrs of the bootstrap peers
+ *   An error, if a peer's multiaddr is invalid
+ */
+func (c Config) BootstrapPeers() ([]multiaddr.Multiaddr, error) {
+	peers := make([]multiaddr.Multiaddr, 0, len(c.Bootstrap.Peers))
+	for _, addr := range c.Bootstrap.Peers {
+		peerAddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		peers = append(peers, peerAddr)
+	}
+	if c.Bootstrap.File != "" {
+		peers = append(peers, util.ReadBootstrapPeers(c.Bootstrap.File)...)
+	}
+	return peers, nil
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Unset every environment variable the config reads for the rest of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	names := []string{ConfigEnv}
+	for _, o := range overrides {
+		names = append(names, o.env)
+	}
+	for _, name := range names {
+		//Setenv restores the variable when the test ends
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+// Write a config file holding content, returning its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Settings come from the defaults, then the config file, then the environment, then the
+// flags set on the command line.
+func TestLoadPrecedence(t *testing.T) {
+	yaml := "grpcPort: 6000\ndht:\n  mode: server\nmarket:\n  republishInterval: 1h\n"
+	other := "grpcPort: 6001\n"
+	tests := []struct {
+		name string
+		// Contents of the config file given with -config, if any
+		file string
+		// Contents of the config file named by ORCANET_CONFIG, if any
+		envFile string
+		env     map[string]string
+		args    []string
+		// Changes the expected config from the defaults
+		want func(c *Config)
+	}{
+		{"defaults", "", "", nil, nil, func(c *Config) {}},
+		{"file over defaults", yaml, "", nil, nil, func(c *Config) {
+			c.GRPCPort = 6000
+			c.DHT.Mode = "server"
+			c.Market.RepublishInterval = time.Hour
+		}},
+		{"file named by the environment", "", yaml, nil, nil, func(c *Config) {
+			c.GRPCPort = 6000
+			c.DHT.Mode = "server"
+			c.Market.RepublishInterval = time.Hour
+		}},
+		{"-config over the environment", other, yaml, nil, nil, func(c *Config) {
+			c.GRPCPort = 6001
+		}},
+		{"environment over file", yaml, "", map[string]string{"ORCANET_GRPC_PORT": "7000", "ORCANET_PROBE_PRIVATE": "true"}, nil, func(c *Config) {
+			c.GRPCPort = 7000
+			c.DHT.Mode = "server"
+			c.Market.RepublishInterval = time.Hour
+			c.Market.Probe.Private = true
+		}},
+		{"flag over environment", yaml, "", map[string]string{"ORCANET_GRPC_PORT": "7000", "ORCANET_DHT_MODE": "client"}, []string{"-port", "8000"}, func(c *Config) {
+			c.GRPCPort = 8000
+			c.DHT.Mode = "client"
+			c.Market.RepublishInterval = time.Hour
+		}},
+		{"flag set to its default", "", "", map[string]string{"ORCANET_GRPC_PORT": "7000"}, []string{"-port", "50051"}, func(c *Config) {}},
+		{"lists", "", "", map[string]string{"ORCANET_LISTEN": "/ip4/127.0.0.1/tcp/1"}, []string{"-listen", "/ip4/127.0.0.1/tcp/2, ,/ip4/127.0.0.1/tcp/3"}, func(c *Config) {
+			c.ListenAddrs = []string{"/ip4/127.0.0.1/tcp/2", "/ip4/127.0.0.1/tcp/3"}
+		}},
+		{"durations", "datastore:\n  gcInterval: 2h\n", "", map[string]string{"ORCANET_WATCH_INTERVAL": "10s"}, []string{"-probe-cache", "0s"}, func(c *Config) {
+			c.Datastore.GCInterval = 2 * time.Hour
+			c.Market.WatchInterval = 10 * time.Second
+			c.Market.Probe.CacheTTL = 0
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			if test.envFile != "" {
+				t.Setenv(ConfigEnv, writeConfig(t, test.envFile))
+			}
+			args := test.args
+			if test.file != "" {
+				args = append([]string{"-config", writeConfig(t, test.file)}, args...)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			loader := Bind(flags, Default())
+			if err := flags.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loader.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Default()
+			test.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// Settings that cannot be read are reported with where they came from.
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		// Part of the error message
+		want string
+	}{
+		{"unknown field in the file", "grpcport: 6000\n", nil, nil, "grpcport"},
+		{"malformed file", "grpcPort: [\n", nil, nil, "config.yaml"},
+		{"invalid environment variable", "", map[string]string{"ORCANET_REPUBLISH": "often"}, nil, "ORCANET_REPUBLISH"},
+		{"invalid setting from a flag", "", nil, []string{"-dht-mode", "sometimes"}, "dht.mode"},
+		{"no registry path", "", map[string]string{"ORCANET_REGISTRY": ""}, nil, "registryPath"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			args := test.args
+			if test.file != "" {
+				args = append([]string{"-config", writeConfig(t, test.file)}, args...)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			loader := Bind(flags, Default())
+			if err := flags.Parse(args); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := loader.Load()
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Load() error = %v, want one mentioning %q", err, test.want)
+			}
+		})
+	}
+}
+
+// Every invalid setting is rejected, named by its field in the config file.
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		// The field named in the error, empty if the config is valid
+		want string
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"memory datastore without a path", func(c *Config) { c.Datastore.Kind, c.Datastore.Path = "memory", "" }, ""},
+		{"no listen address", func(c *Config) { c.ListenAddrs = nil }, "listenAddrs"},
+		{"invalid listen address", func(c *Config) { c.ListenAddrs = []string{"localhost:4000"} }, "listenAddrs"},
+		{"gRPC port out of range", func(c *Config) { c.GRPCPort = 65536 }, "grpcPort"},
+		{"negative gRPC port", func(c *Config) { c.GRPCPort = -1 }, "grpcPort"},
+		{"no key path", func(c *Config) { c.KeyPath = "" }, "keyPath"},
+		{"unknown key type", func(c *Config) { c.KeyType = "dsa" }, "keyType"},
+		{"bootstrap peer without a peer ID", func(c *Config) { c.Bootstrap.Peers = []string{"/ip4/127.0.0.1/tcp/4001"} }, "bootstrap.peers"},
+		{"unknown DHT mode", func(c *Config) { c.DHT.Mode = "sometimes" }, "dht.mode"},
+		{"no protocol prefix", func(c *Config) { c.DHT.ProtocolPrefix = "" }, "dht.protocolPrefix"},
+		{"unknown datastore", func(c *Config) { c.Datastore.Kind = "sqlite" }, "datastore.kind"},
+		{"on-disk datastore without a path", func(c *Config) { c.Datastore.Path = "" }, "datastore.path"},
+		{"no GC interval", func(c *Config) { c.Datastore.GCInterval = 0 }, "datastore.gcInterval"},
+		{"unknown storage", func(c *Config) { c.Market.Storage = "files" }, "market.storage"},
+		{"no republish interval", func(c *Config) { c.Market.RepublishInterval = 0 }, "market.republishInterval"},
+		{"no batch parallelism", func(c *Config) { c.Market.BatchParallelism = 0 }, "market.batchParallelism"},
+		{"no watch interval", func(c *Config) { c.Market.WatchInterval = -time.Second }, "market.watchInterval"},
+		{"no probe timeout", func(c *Config) { c.Market.Probe.Timeout = 0 }, "market.probe.timeout"},
+		{"negative probe cache TTL", func(c *Config) { c.Market.Probe.CacheTTL = -time.Second }, "market.probe.cacheTTL"},
+	}
+	for _, test := range tests {
+		c := Default()
+		test.change(&c)
+		err := c.Validate()
+		if test.want == "" {
+			if err != nil {
+				t.Errorf("%s: Validate() = %v, want nil", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasPrefix(err.Error(), test.want+":") {
+			t.Errorf("%s: Validate() = %v, want an error naming %s", test.name, err, test.want)
+		}
+	}
+}
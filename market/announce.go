@@ -0,0 +1,318 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	p2precord "github.com/libp2p/go-libp2p-record"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+const (
+	// Announcements of entries registered longer ago than this are not passed on, so old
+	// entries cannot be replayed as new registrations.
+	maxAnnouncementAge = 5 * time.Minute
+	// How many announcements a subscriber may fall behind by before announcements are
+	// dropped for it.
+	announcementBuffer = 64
+)
+
+/*
+ * The GossipSub topic registrations are announced on, under the DHT protocol prefix so
+ * separate networks do not relay each other's announcements.
+ *
+ * Parameters:
+ *   protocolPrefix: The prefix of the DHT protocol IDs, e.g. orcanet/market
+ *
+ * Returns:
+ *   The topic, e.g. orcanet/market/announce
+ */
+func AnnounceTopic(protocolPrefix string) string {
+	return protocolPrefix + "/announce"
+}
+
+// Gossips this node's registrations on its announce topic and passes the registrations
+// announced by other nodes on to local subscribers.
+type Announcer struct {
+	topic *pubsub.Topic
+	sub   *pubsub.Subscription
+
+	mu          sync.Mutex
+	subscribers map[chan *Announcement]struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+/*
+ * Join an announce topic. Incoming announcements are checked with v exactly as the
+ * entry they carry would be checked in a holder chain, so only entries the DHT would
+ * accept are relayed.
+ *
+ * Parameters:
+ *   ps: The pubsub router of the host
+ *   topicName: The topic to announce on, see AnnounceTopic
+ *   v: The validator for holder chains
+ *
+ * Returns:
+ *   An announcer, which passes nothing on to subscribers until Start is called
+ *   An error, if the topic could not be joined
+ */
+func NewAnnouncer(ps *pubsub.PubSub, topicName string, v p2precord.Validator) (*Announcer, error) {
+	validate := func(ctx context.Context, from peer.ID, msg *pubsub.Message) pubsub.ValidationResult {
+		announcement, result := announcementResult(v, msg.GetData(), time.Now())
+		msg.ValidatorData = announcement
+		return result
+	}
+	if err := ps.RegisterTopicValidator(topicName, validate); err != nil {
+		return nil, err
+	}
+
+	topic, err := ps.Join(topicName)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := topic.Subscribe()
+	if err != nil {
+		topic.Close()
+		return nil, err
+	}
+	return &Announcer{
+		topic:       topic,
+		sub:         sub,
+		subscribers: make(map[chan *Announcement]struct{}),
+	}, nil
+}
+
+var errStaleAnnouncement = errors.New("announced entry is too old")
+
+/*
+ * Decide what the topic does with an announcement. A stale one is ignored rather than
+ * rejected, as peers relay announcements they accepted before they went stale and should
+ * not be penalized for it.
+ *
+ * Parameters:
+ *   v: The validator for holder chains
+ *   data: The announcement as published
+ *   now: The current time
+ *
+ * Returns:
+ *   The announcement, if it is accepted
+ *   Whether it is accepted, ignored or rejected
+ */
+func announcementResult(v p2precord.Validator, data []byte, now time.Time) (*Announcement, pubsub.ValidationResult) {
+	announcement, err := validateAnnouncement(v, data, now)
+	if errors.Is(err, errStaleAnnouncement) {
+		return nil, pubsub.ValidationIgnore
+	}
+	if err != nil {
+		return nil, pubsub.ValidationReject
+	}
+	return announcement, pubsub.ValidationAccept
+}
+
+/*
+ * Decode an announcement from the topic and check the entry it carries.
+ *
+ * Parameters:
+ *   v: The validator for holder chains
+ *   data: The announcement as published
+ *   now: The current time
+ *
+ * Returns:
+ *   The announcement, with its holder decoded from the entry
+ *   errStaleAnnouncement if the entry was registered too long ago, or any other error if
+ *   the announcement is not valid
+ */
+func validateAnnouncement(v p2precord.Validator, data []byte, now time.Time) (*Announcement, error) {
+	announcement := &Announcement{}
+	if err := proto.Unmarshal(data, announcement); err != nil {
+		return nil, err
+	}
+	//a file has a single key, so its announcements must use the same form of its hash
+	hash, err := util.NormalizeFileHash(announcement.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	if hash != announcement.GetFileHash() {
+		return nil, fmt.Errorf("file hash %s is not normalized", announcement.GetFileHash())
+	}
+
+	signed := announcement.GetEntry()
+	if signed == nil || signed.GetFlags() > 0xFF {
+		return nil, errors.New("invalid entry")
+	}
+	entry := signed.toRecord()
+	if entry.Withdrawn() {
+		return nil, errors.New("withdrawn entries are not announced")
+	}
+	value, err := record.Encode(&record.Chain{Entries: []record.Entry{entry}})
+	if err != nil {
+		return nil, err
+	}
+	if err := v.Validate(chainKey(hash), value); err != nil {
+		return nil, err
+	}
+	//the validator accepts expired entries, which are not worth announcing either
+	if entry.Expired(uint64(now.UTC().Unix())) || entry.RegisteredAt+uint64(maxAnnouncementAge.Seconds()) < uint64(now.UTC().Unix()) {
+		return nil, errStaleAnnouncement
+	}
+
+	announcement.Holder = &User{}
+	if err := proto.Unmarshal(entry.Message, announcement.Holder); err != nil {
+		return nil, err
+	}
+	return announcement, nil
+}
+
+// Start passing announcements on to subscribers in the background until Stop is called.
+func (a *Announcer) Start(ctx context.Context) {
+	ctx, a.cancel = context.WithCancel(ctx)
+	a.done = make(chan struct{})
+	go a.run(ctx)
+}
+
+// Stop passing announcements on, close every subscription and leave the topic.
+func (a *Announcer) Stop() {
+	if a.cancel != nil {
+		a.cancel()
+		<-a.done
+	}
+	a.sub.Cancel()
+	a.topic.Close()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for feed := range a.subscribers {
+		close(feed)
+	}
+	a.subscribers = nil
+}
+
+func (a *Announcer) run(ctx context.Context) {
+	defer close(a.done)
+	for {
+		msg, err := a.sub.Next(ctx)
+		if err != nil {
+			return
+		}
+		announcement, ok := msg.ValidatorData.(*Announcement)
+		if !ok {
+			continue
+		}
+
+		a.mu.Lock()
+		for feed := range a.subscribers {
+			select {
+			case feed <- announcement:
+			default:
+				//a slow subscriber must not hold up the others
+			}
+		}
+		a.mu.Unlock()
+	}
+}
+
+/*
+ * Announce a registration on the topic.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the registered file
+ *   entry: The entry added to the chain for the file
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (a *Announcer) Publish(ctx context.Context, hash string, entry *SignedEntry) error {
+	data, err := proto.Marshal(&Announcement{FileHash: hash, Entry: entry})
+	if err != nil {
+		return err
+	}
+	return a.topic.Publish(ctx, data)
+}
+
+/*
+ * Subscribe to the announcements received from now on, including this node's own.
+ * Announcements are dropped for a subscriber that falls too far behind.
+ *
+ * Returns:
+ *   The feed of announcements, closed when the announcer stops
+ *   A function that ends the subscription
+ */
+func (a *Announcer) Subscribe() (<-chan *Announcement, func()) {
+	feed := make(chan *Announcement, announcementBuffer)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.subscribers == nil {
+		close(feed)
+		return feed, func() {}
+	}
+	a.subscribers[feed] = struct{}{}
+
+	return feed, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if _, ok := a.subscribers[feed]; ok {
+			delete(a.subscribers, feed)
+			close(feed)
+		}
+	}
+}
+
+// Announce a registration if the server has an announcer. Registrations still reach the
+// network through the DHT if this fails, so a failure is only logged.
+func (s *Server) announce(ctx context.Context, hash string, entry *SignedEntry) {
+	if s.Announcer == nil {
+		return
+	}
+	if err := s.Announcer.Publish(ctx, hash, entry); err != nil {
+		log.Printf("Failed to announce %s: %v", hash, err)
+	}
+}
+
+/*
+ * gRPC service to stream the registrations announced on the network, including this
+ * node's own, as they arrive. Announcements that arrive while the client is too far
+ * behind are dropped.
+ *
+ * Parameters:
+ *   stream: The stream to send Announcements on
+ *
+ * Returns:
+ *   Nil once the client cancels, an Unavailable status if announcements are disabled or
+ *   stop, or an error if an announcement could not be sent
+ */
+func (s *Server) SubscribeAnnouncements(_ *emptypb.Empty, stream Market_SubscribeAnnouncementsServer) error {
+	if s.Announcer == nil {
+		return status.Error(codes.Unavailable, "announcements are disabled on this server")
+	}
+	ctx := stream.Context()
+	feed, unsubscribe := s.Announcer.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case announcement, ok := <-feed:
+			if !ok {
+				return status.Error(codes.Unavailable, "announcements have stopped")
+			}
+			if err := stream.Send(announcement); err != nil {
+				return err
+			}
+		}
+	}
+}
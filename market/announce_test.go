@@ -0,0 +1,135 @@
+package market
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// Checks the signature of every entry of a holder chain, which is all of the validator an
+// announcement depends on. The validator package cannot be imported here.
+type signatureValidator struct{}
+
+func (signatureValidator) Validate(key string, value []byte) error {
+	chain, err := record.Decode(value)
+	if err != nil {
+		return err
+	}
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		user := &User{}
+		if err := proto.Unmarshal(entry.Message, user); err != nil {
+			return err
+		}
+		publicKey, err := util.UnmarshalUserID(user.GetId())
+		if err != nil {
+			return err
+		}
+		valid, err := publicKey.Verify(entry.SigningPayload(strings.TrimPrefix(key, "orcanet/market/")), entry.Signature)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("Signature invalid!")
+		}
+	}
+	return nil
+}
+
+func (signatureValidator) Select(key string, values [][]byte) (int, error) {
+	return 0, nil
+}
+
+// Only valid, recent registrations of a normalized hash are announced. Stale ones are
+// told apart so they are ignored rather than held against the peer relaying them.
+func TestValidateAnnouncement(t *testing.T) {
+	const hash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(hash string, ttl uint32, flags byte) *SignedEntry {
+		signed, err := SignEntry(privKey, hash, &User{Name: "holder", Ip: "203.0.113.7", Port: 4000, Price: 5}, ttl, flags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	forged := sign(hash, 600, 0)
+	forged.Signature[0] ^= 0xFF
+	truncated := sign(hash, 600, 0)
+	truncated.Signature = truncated.Signature[:len(truncated.Signature)/2]
+	repriced := sign(hash, 600, 0)
+	holder := &User{}
+	if err := proto.Unmarshal(repriced.GetUser(), holder); err != nil {
+		t.Fatal(err)
+	}
+	holder.Price = 1
+	if repriced.User, err = proto.Marshal(holder); err != nil {
+		t.Fatal(err)
+	}
+	overflowing := sign(hash, 600, 0)
+	overflowing.Flags = 0x100
+
+	now := time.Now()
+	tests := []struct {
+		name  string
+		hash  string
+		entry *SignedEntry
+		now   time.Time
+		// Whether the announcement is accepted, and if not whether it is only stale
+		ok    bool
+		stale bool
+	}{
+		{"valid", hash, sign(hash, 600, 0), now, true, false},
+		{"valid near the end of its age", hash, sign(hash, 600, 0), now.Add(maxAnnouncementAge - time.Minute), true, false},
+		{"uppercase hash", strings.ToUpper(hash), sign(hash, 600, 0), now, false, false},
+		{"CID of the hash", "bafkreie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba", sign(hash, 600, 0), now, false, false},
+		{"malformed hash", "not-a-hash", sign(hash, 600, 0), now, false, false},
+		{"no entry", hash, nil, now, false, false},
+		{"flags over a byte", hash, overflowing, now, false, false},
+		{"withdrawn", hash, sign(hash, 600, record.FlagWithdrawn), now, false, false},
+		{"registered too long ago", hash, sign(hash, 3600, 0), now.Add(maxAnnouncementAge + time.Minute), false, true},
+		{"expired", hash, sign(hash, 60, 0), now.Add(2 * time.Minute), false, true},
+		{"forged signature", hash, forged, now, false, false},
+		{"truncated signature", hash, truncated, now, false, false},
+		{"changed message", hash, repriced, now, false, false},
+		{"signed for another file", hash, sign("60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752", 600, 0), now, false, false},
+	}
+	for _, test := range tests {
+		data, err := proto.Marshal(&Announcement{FileHash: test.hash, Entry: test.entry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		announcement, err := validateAnnouncement(signatureValidator{}, data, test.now)
+		if (err == nil) != test.ok || errors.Is(err, errStaleAnnouncement) != test.stale {
+			t.Errorf("%s: validateAnnouncement error = %v, want ok %v and stale %v", test.name, err, test.ok, test.stale)
+			continue
+		}
+		if test.ok && (announcement.GetHolder().GetPrice() != 5 || announcement.GetFileHash() != hash) {
+			t.Errorf("%s: validateAnnouncement decoded %v, want the holder asking 5 for %s", test.name, announcement, hash)
+		}
+
+		want := pubsub.ValidationReject
+		if test.ok {
+			want = pubsub.ValidationAccept
+		} else if test.stale {
+			want = pubsub.ValidationIgnore
+		}
+		if _, result := announcementResult(signatureValidator{}, data, test.now); result != want {
+			t.Errorf("%s: the topic validator returned %v, want %v", test.name, result, want)
+		}
+	}
+
+	if _, result := announcementResult(signatureValidator{}, []byte{0xFF, 0xFF}, now); result != pubsub.ValidationReject {
+		t.Errorf("the topic validator returned %v for garbage, want it rejected", result)
+	}
+}
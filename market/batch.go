@@ -0,0 +1,129 @@
+package market
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// How many files of a batch are worked on at once when the server does not set a limit.
+	DefaultBatchParallelism = 16
+	// Most files a single batch request may hold.
+	MaxBatchSize = 256
+)
+
+/*
+ * gRPC service to register many files at once. Each file is registered as RegisterFile
+ * would, with up to BatchParallelism DHT lookups and puts running concurrently. A file
+ * that fails, or is missing its user, does not stop the others.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf RegisterFilesRequest struct holding a RegisterFileRequest per file.
+ *
+ * Returns:
+ *   A RegisterFilesResponse holding a result for each file, in request order
+ *   An InvalidArgument status if there are more than MaxBatchSize files, or any other error
+ */
+func (s *Server) RegisterFiles(ctx context.Context, in *RegisterFilesRequest) (*RegisterFilesResponse, error) {
+	files := in.GetFiles()
+	if err := checkBatchSize(len(files)); err != nil {
+		return nil, err
+	}
+	results := make([]*RegisterFileResult, len(files))
+	//an empty item or one without a user is reported on its own without being worked on
+	for i, file := range files {
+		if file == nil || file.GetUser() == nil {
+			err := status.Error(codes.InvalidArgument, "file and user are required")
+			results[i] = &RegisterFileResult{FileHash: file.GetFileHash(), Error: itemError(err)}
+		}
+	}
+	s.forEachConcurrently(len(files), func(i int) {
+		if results[i] != nil {
+			return
+		}
+		_, err := s.RegisterFile(ctx, files[i])
+		results[i] = &RegisterFileResult{FileHash: files[i].GetFileHash(), Error: itemError(err)}
+	})
+	return &RegisterFilesResponse{Results: results}, nil
+}
+
+/*
+ * gRPC service to check for the holders of many files at once. Each file is looked up as
+ * CheckHolders would, with up to BatchParallelism DHT lookups running concurrently, and
+ * its result carries everything CheckHolders would return for it.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf CheckHoldersBatchRequest struct holding a CheckHoldersRequest per file.
+ *
+ * Returns:
+ *   A CheckHoldersBatchResponse holding the holders or an error for each file, in request order
+ *   An InvalidArgument status if there are more than MaxBatchSize files, or any other error
+ */
+func (s *Server) CheckHoldersBatch(ctx context.Context, in *CheckHoldersBatchRequest) (*CheckHoldersBatchResponse, error) {
+	files := in.GetFiles()
+	if err := checkBatchSize(len(files)); err != nil {
+		return nil, err
+	}
+	results := make([]*CheckHoldersResult, len(files))
+	s.forEachConcurrently(len(files), func(i int) {
+		holders, err := s.CheckHolders(ctx, files[i])
+		results[i] = &CheckHoldersResult{
+			FileHash:      files[i].GetFileHash(),
+			Holders:       holders.GetHolders(),
+			Error:         itemError(err),
+			NextPageToken: holders.GetNextPageToken(),
+			Details:       holders.GetDetails(),
+		}
+	})
+	return &CheckHoldersBatchResponse{Results: results}, nil
+}
+
+/*
+ * Call fn for every index below n, running at most BatchParallelism calls at once, and
+ * wait for all of them to return.
+ */
+func (s *Server) forEachConcurrently(n int, fn func(i int)) {
+	limit := s.BatchParallelism
+	if limit <= 0 {
+		limit = DefaultBatchParallelism
+	}
+
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// Reject a batch request holding more than MaxBatchSize files.
+func checkBatchSize(n int) error {
+	if n > MaxBatchSize {
+		return status.Errorf(codes.InvalidArgument, "at most %d files are allowed per batch, got %d", MaxBatchSize, n)
+	}
+	return nil
+}
+
+// Describe the error of a single batch item, nil if it succeeded.
+func itemError(err error) *ItemError {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		//maps context errors to Canceled and DeadlineExceeded, anything else to Unknown
+		st = status.FromContextError(err)
+	}
+	return &ItemError{Code: uint32(st.Code()), Message: st.Message()}
+}
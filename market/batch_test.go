@@ -0,0 +1,47 @@
+package market
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Batches over MaxBatchSize are rejected before any file is worked on.
+func TestBatchSizeLimit(t *testing.T) {
+	s := &Server{}
+	_, err := s.RegisterFiles(context.Background(), &RegisterFilesRequest{Files: make([]*RegisterFileRequest, MaxBatchSize+1)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("RegisterFiles with %d files returned %v, want InvalidArgument", MaxBatchSize+1, err)
+	}
+	_, err = s.CheckHoldersBatch(context.Background(), &CheckHoldersBatchRequest{Files: make([]*CheckHoldersRequest, MaxBatchSize+1)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CheckHoldersBatch with %d files returned %v, want InvalidArgument", MaxBatchSize+1, err)
+	}
+	response, err := s.CheckHoldersBatch(context.Background(), &CheckHoldersBatchRequest{})
+	if err != nil || len(response.GetResults()) != 0 {
+		t.Errorf("empty CheckHoldersBatch returned %v, %v", response, err)
+	}
+}
+
+// Items that are empty or missing their user fail on their own without stopping the batch.
+func TestRegisterFilesRejectsIncompleteItems(t *testing.T) {
+	s := &Server{}
+	files := []*RegisterFileRequest{nil, {FileHash: "QmTest"}}
+	response, err := s.RegisterFiles(context.Background(), &RegisterFilesRequest{Files: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.GetResults()) != len(files) {
+		t.Fatalf("got %d results for %d files", len(response.GetResults()), len(files))
+	}
+	for i, result := range response.GetResults() {
+		if codes.Code(result.GetError().GetCode()) != codes.InvalidArgument {
+			t.Errorf("file %d returned %v, want InvalidArgument", i, result.GetError())
+		}
+	}
+	if hash := response.GetResults()[1].GetFileHash(); hash != "QmTest" {
+		t.Errorf("file 1 reported hash %q, want QmTest", hash)
+	}
+}
@@ -0,0 +1,65 @@
+package market
+
+import (
+	"context"
+	"log"
+)
+
+/*
+ * Look up the extra details a CheckHolders request asked for, for every holder in a page.
+ * Holders are looked up concurrently, like the files of a batch. A detail that cannot be
+ * looked up is left unset rather than failing the request.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the file
+ *   users: The holders in the page
+ *   in: The request saying which details to look up
+ *
+ * Returns:
+ *   The details of each holder, in the same order as users
+ */
+func (s *Server) holderDetails(ctx context.Context, hash string, users []*User, in *CheckHoldersRequest) []*HolderDetails {
+	details := make([]*HolderDetails, len(users))
+
+	//the metadata is the same for every holder, so it is looked up once
+	var claims []*FileMetadata
+	var agreed *FileMetadata
+	if in.GetWithCost() {
+		var err error
+		claims, err = s.metadataClaims(ctx, hash)
+		if err != nil {
+			log.Printf("Failed to look up metadata of %s: %v", hash, err)
+		}
+		agreed = agreedMetadata(claims)
+	}
+
+	s.forEachConcurrently(len(users), func(i int) {
+		details[i] = &HolderDetails{}
+		if in.GetWithReputation() {
+			producer, err := producerPeer(users[i].GetId())
+			if err == nil {
+				details[i].Reputation, err = s.reputation(ctx, producer)
+			}
+			if err != nil {
+				log.Printf("Failed to look up reputation of %s: %v", users[i].GetName(), err)
+			} else {
+				//the individual ratings are left to GetReputation to keep the response small
+				details[i].Reputation.Ratings = nil
+			}
+		}
+		if in.GetProbe() && s.Prober != nil {
+			details[i].Liveness = s.Prober.Probe(ctx, users[i])
+		}
+		if size, ok := holderFileSize(claims, agreed, users[i]); ok {
+			details[i].FileSize = &size
+			total, err := totalPrice(users[i].GetPrice(), size)
+			if err != nil {
+				log.Printf("Failed to work out the total price of %s: %v", users[i].GetName(), err)
+			} else {
+				details[i].TotalPrice = &total
+			}
+		}
+	})
+	return details
+}
@@ -0,0 +1,553 @@
+package market_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/routing"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"orcanet/market"
+	"orcanet/record"
+	"orcanet/util"
+	"orcanet/validator"
+)
+
+const testHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+/*
+ * Start a market server whose DHT node is connected to one other node over an in-memory
+ * network, so its puts reach a peer and its lookups find what either node stores.
+ *
+ * Parameters:
+ *   v: The validator of both DHT nodes and the server
+ *
+ * Returns:
+ *   The server, signing with the key of its DHT node
+ */
+func newTestServer(t *testing.T, v market.ChainValidator) *market.Server {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	mn := mocknet.New()
+	t.Cleanup(func() { mn.Close() })
+	nodes := make([]*dht.IpfsDHT, 2)
+	for i := range nodes {
+		host, err := mn.GenPeer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i], err = dht.New(ctx, host, dht.Mode(dht.ModeServer), dht.ProtocolPrefix("/orcanet-test"), dht.Validator(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { nodes[i].Close() })
+	}
+	if err := mn.LinkAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mn.ConnectAllButSelf(); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); nodes[0].RoutingTable().Size() == 0 || nodes[1].RoutingTable().Size() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the DHT nodes did not find each other")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//the server signs with the key of its host, so its profiles belong to its provider records
+	privKey := nodes[0].Host().Peerstore().PrivKey(nodes[0].PeerID())
+	return &market.Server{K_DHT: nodes[0], PrivKey: privKey, PubKey: privKey.GetPublic(), V: v}
+}
+
+// Validates like the OrcaNet namespaces, but fails to merge the values of one key.
+type failingMerge struct {
+	*validator.NamespacedValidator
+	key string
+}
+
+func (v failingMerge) Merge(key string, values [][]byte) ([]byte, error) {
+	if key == v.key {
+		return nil, errors.New("merge failed")
+	}
+	return v.NamespacedValidator.Merge(key, values)
+}
+
+// A lookup that fails is reported on its own batch item, while a file nobody registered
+// is an empty result rather than an error.
+func TestCheckHoldersBatchLookupError(t *testing.T) {
+	v := failingMerge{NamespacedValidator: validator.NewOrcaNamespaces(), key: validator.MarketPrefix + testHash}
+	s := newTestServer(t, v)
+	ctx := context.Background()
+	if _, err := s.RegisterFile(ctx, &market.RegisterFileRequest{FileHash: testHash, User: &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	unregistered := strings.Repeat("ab", 32)
+	response, err := s.CheckHoldersBatch(ctx, &market.CheckHoldersBatchRequest{Files: []*market.CheckHoldersRequest{
+		{FileHash: unregistered},
+		{FileHash: testHash},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := response.GetResults()
+	if len(results) != 2 {
+		t.Fatalf("got %d results for 2 files", len(results))
+	}
+	if results[0].GetError() != nil || len(results[0].GetHolders()) != 0 {
+		t.Errorf("the unregistered file returned %v, %v, want no holders and no error", results[0].GetHolders(), results[0].GetError())
+	}
+	if codes.Code(results[1].GetError().GetCode()) != codes.Unknown || !strings.Contains(results[1].GetError().GetMessage(), "merge failed") {
+		t.Errorf("the file whose lookup failed returned %v, want the merge error", results[1].GetError())
+	}
+}
+
+/*
+ * Sign a holder record for a file with a new Ed25519 key.
+ *
+ * Parameters:
+ *   registeredAt: The unix time the record was signed at
+ *   ttl: Seconds the record stays valid for
+ *
+ * Returns:
+ *   The signed record
+ *   The shard of the holder chain it belongs in
+ */
+func signedHolder(t *testing.T, registeredAt uint64, ttl uint32) (record.Entry, int) {
+	t.Helper()
+	privKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := util.MarshalUserID(privKey.GetPublic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := proto.Marshal(&market.User{Id: id, Name: "holder", Ip: "203.0.113.8", Port: 4000, Price: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := record.Entry{RegisteredAt: registeredAt, TTL: ttl, Message: message}
+	entry.Signature, err = privKey.Sign(entry.SigningPayload(testHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical, err := util.CanonicalUserID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry, record.Shard(canonical)
+}
+
+// Put a chain on the DHT of a test server.
+func putChain(t *testing.T, s *market.Server, key string, entries []record.Entry) {
+	t.Helper()
+	value, err := record.Encode(&record.Chain{Entries: entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.K_DHT.PutValue(context.Background(), key, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Look up the chain stored under a key of the DHT of a test server.
+func storedChain(t *testing.T, s *market.Server, key string) *record.Chain {
+	t.Helper()
+	value, err := s.K_DHT.GetValue(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := record.Decode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain
+}
+
+/*
+ * Store a holder chain for testHash along with its shards.
+ *
+ * Parameters:
+ *   chain: The records of the chain under orcanet/market/<hash>
+ *   sharded: The records to put in the shards they belong in
+ *   shards: The shard each record of sharded belongs in
+ */
+func putShardedChain(t *testing.T, s *market.Server, chain []record.Entry, sharded []record.Entry, shards []int) {
+	t.Helper()
+	putChain(t, s, validator.MarketPrefix+testHash, chain)
+	byShard := make(map[int][]record.Entry)
+	for i := range sharded {
+		byShard[shards[i]] = append(byShard[shards[i]], sharded[i])
+	}
+	for shard, entries := range byShard {
+		putChain(t, s, fmt.Sprintf("%s%s/%d", validator.MarketPrefix, testHash, shard), entries)
+	}
+}
+
+// Signatures of the records of a chain.
+func signatures(chain *record.Chain) map[string]bool {
+	found := make(map[string]bool)
+	for i := range chain.Entries {
+		found[string(chain.Entries[i].Signature)] = true
+	}
+	return found
+}
+
+// While the chain is full, registering puts it back without its expired records but
+// holding every live record it held, even though the shards hold newer records, so the
+// DHT selects it over the stored copy.
+func TestRegisterRefreshesFullChain(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	now := uint64(time.Now().UTC().Unix())
+	chain := make([]record.Entry, record.ShardThreshold+2)
+	for i := range chain {
+		if i < 2 {
+			chain[i], _ = signedHolder(t, now-7200, 3600)
+		} else {
+			chain[i], _ = signedHolder(t, now-600, 3600)
+		}
+	}
+	sharded := make([]record.Entry, 4)
+	shards := make([]int, len(sharded))
+	for i := range sharded {
+		sharded[i], shards[i] = signedHolder(t, now-60, 3600)
+	}
+	putShardedChain(t, s, chain, sharded, shards)
+
+	if _, err := s.RegisterFile(context.Background(), &market.RegisterFileRequest{FileHash: testHash, User: &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	refreshed := storedChain(t, s, validator.MarketPrefix+testHash)
+	if len(refreshed.Entries) != record.ShardThreshold {
+		t.Errorf("the chain holds %d records, want %d", len(refreshed.Entries), record.ShardThreshold)
+	}
+	stored := signatures(refreshed)
+	for i := 2; i < len(chain); i++ {
+		if !stored[string(chain[i].Signature)] {
+			t.Fatalf("the chain lost live record %d", i)
+		}
+	}
+	holders, err := s.CheckHolders(context.Background(), &market.CheckHoldersRequest{FileHash: testHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders.GetHolders()) != len(chain)-2+len(sharded)+1 {
+		t.Errorf("found %d holders, want %d", len(holders.GetHolders()), len(chain)-2+len(sharded)+1)
+	}
+}
+
+// Once enough records of a full chain have expired, registering puts the chain back
+// holding the live records of the chain and its shards along with ours, and nothing else.
+func TestRegisterUnshardsChain(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	now := uint64(time.Now().UTC().Unix())
+	chain := make([]record.Entry, record.ShardThreshold)
+	for i := range chain {
+		if i < 10 {
+			chain[i], _ = signedHolder(t, now-7200, 3600)
+		} else {
+			chain[i], _ = signedHolder(t, now-600, 3600)
+		}
+	}
+	sharded := make([]record.Entry, 2)
+	shards := make([]int, len(sharded))
+	for i := range sharded {
+		sharded[i], shards[i] = signedHolder(t, now-60, 3600)
+	}
+	putShardedChain(t, s, chain, sharded, shards)
+
+	if _, err := s.RegisterFile(context.Background(), &market.RegisterFileRequest{FileHash: testHash, User: &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	unsharded := storedChain(t, s, validator.MarketPrefix+testHash)
+	stored := signatures(unsharded)
+	if len(unsharded.Entries) != record.ShardThreshold-10+len(sharded)+1 {
+		t.Errorf("the chain holds %d records, want %d", len(unsharded.Entries), record.ShardThreshold-10+len(sharded)+1)
+	}
+	for i, entry := range append(chain[10:], sharded...) {
+		if !stored[string(entry.Signature)] {
+			t.Errorf("the chain is missing live record %d", i)
+		}
+	}
+	for i := range unsharded.Entries {
+		if unsharded.Entries[i].Expired(now) {
+			t.Errorf("the chain kept an expired record")
+		}
+	}
+	if ourEntry(t, s, unsharded) == nil {
+		t.Error("the chain is missing our record")
+	}
+}
+
+// The record of the test server's own key in a holder chain, or nil if it holds none.
+func ourEntry(t *testing.T, s *market.Server, chain *record.Chain) *record.Entry {
+	t.Helper()
+	ours, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range chain.Entries {
+		user := &market.User{}
+		if err := proto.Unmarshal(chain.Entries[i].Message, user); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(user.GetId(), ours) {
+			return &chain.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Provider records are looked up only by a server in provider mode.
+func TestProviderHoldersOnlyInProviderMode(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	s.Storage = market.ProviderStorage
+	ctx := context.Background()
+	if _, err := s.RegisterFile(ctx, &market.RegisterFileRequest{FileHash: testHash, User: &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		storage market.StorageMode
+		want    int
+	}{
+		{market.ProviderStorage, 1},
+		{market.ChainStorage, 0},
+	} {
+		s.Storage = test.storage
+		holders, err := s.CheckHolders(ctx, &market.CheckHoldersRequest{FileHash: testHash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(holders.GetHolders()) != test.want {
+			t.Errorf("storage mode %d found %d holders, want %d", test.storage, len(holders.GetHolders()), test.want)
+		}
+	}
+}
+
+// A listing registered while the republisher sleeps through its long interval is still
+// put again before its short TTL runs out.
+func TestRepublishShortTTLWhileIdle(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	s.Republisher = market.NewRepublisher(s, time.Hour)
+	s.Republisher.Start(context.Background())
+	t.Cleanup(s.Republisher.Stop)
+
+	const ttl = 4
+	if _, err := s.RegisterFile(context.Background(), &market.RegisterFileRequest{FileHash: testHash, Ttl: ttl, User: &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	registered := ourEntry(t, s, storedChain(t, s, validator.MarketPrefix+testHash))
+	if registered == nil {
+		t.Fatal("the chain is missing our record")
+	}
+
+	for {
+		entry := ourEntry(t, s, storedChain(t, s, validator.MarketPrefix+testHash))
+		if entry != nil && entry.RegisteredAt > registered.RegisteredAt {
+			return
+		}
+		if registered.Expired(uint64(time.Now().UTC().Unix())) {
+			t.Fatal("the listing expired before it was republished")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// RegisterSignedEntry rejects entries that would not register the client, and merges a
+// valid one into the chain as it was signed.
+func TestRegisterSignedEntry(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	ctx := context.Background()
+	client, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(hash string, flags byte) *market.SignedEntry {
+		t.Helper()
+		signed, err := market.SignEntry(client, hash, &market.User{Name: "client", Ip: "203.0.113.9", Port: 4000, Price: 2}, 3600, flags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	forged := sign(testHash, 0)
+	forged.Signature[0] ^= 0xFF
+	truncated := sign(testHash, 0)
+	truncated.Signature = truncated.Signature[:len(truncated.Signature)/2]
+	expiredEntry, _ := signedHolder(t, uint64(time.Now().UTC().Unix())-7200, 3600)
+	expired := &market.SignedEntry{User: expiredEntry.Message, RegisteredAt: expiredEntry.RegisteredAt, Ttl: expiredEntry.TTL, Signature: expiredEntry.Signature}
+
+	for _, test := range []struct {
+		name  string
+		entry *market.SignedEntry
+	}{
+		{"signed for another file", sign(strings.Repeat("ab", 32), 0)},
+		{"forged signature", forged},
+		{"truncated signature", truncated},
+		{"withdrawn", sign(testHash, record.FlagWithdrawn)},
+		{"expired", expired},
+	} {
+		_, err := s.RegisterSignedEntry(ctx, &market.RegisterSignedEntryRequest{FileHash: testHash, Entry: test.entry})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: RegisterSignedEntry = %v, want InvalidArgument", test.name, err)
+		}
+	}
+	if _, err := s.K_DHT.GetValue(ctx, validator.MarketPrefix+testHash); !errors.Is(err, routing.ErrNotFound) {
+		t.Fatalf("a rejected entry was put: %v", err)
+	}
+
+	if _, err := s.RegisterSignedEntry(ctx, &market.RegisterSignedEntryRequest{FileHash: testHash, Entry: sign(testHash, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	holders, err := s.CheckHolders(ctx, &market.CheckHoldersRequest{FileHash: testHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientID, err := util.MarshalUserID(client.GetPublic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders.GetHolders()) != 1 || !bytes.Equal(holders.GetHolders()[0].GetId(), clientID) || holders.GetHolders()[0].GetPrice() != 2 {
+		t.Errorf("CheckHolders = %v, want the client's listing", holders.GetHolders())
+	}
+}
+
+/*
+ * Put a chain for testHash holding only a listing of the server, signed with its key.
+ *
+ * Parameters:
+ *   user: The details listed, the server's id is filled in
+ *   age: How many seconds ago the listing was registered
+ *
+ * Returns:
+ *   The listing
+ */
+func putOurListing(t *testing.T, s *market.Server, user *market.User, age uint64) record.Entry {
+	t.Helper()
+	id, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user = proto.Clone(user).(*market.User)
+	user.Id = id
+	message, err := proto.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listing := record.Entry{RegisteredAt: uint64(time.Now().UTC().Unix()) - age, TTL: 600, Message: message}
+	listing.Signature, err = s.PrivKey.Sign(listing.SigningPayload(testHash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	putChain(t, s, validator.MarketPrefix+testHash, []record.Entry{listing})
+	return listing
+}
+
+// Validates like the OrcaNet namespaces, but rejects holder chains holding a withdrawal.
+type rejectWithdrawals struct {
+	*validator.NamespacedValidator
+}
+
+func (v rejectWithdrawals) Validate(key string, value []byte) error {
+	if strings.HasPrefix(key, validator.MarketPrefix) {
+		chain, err := record.Decode(value)
+		if err != nil {
+			return err
+		}
+		for i := range chain.Entries {
+			if chain.Entries[i].Withdrawn() {
+				return errors.New("withdrawals rejected")
+			}
+		}
+	}
+	return v.NamespacedValidator.Validate(key, value)
+}
+
+// UnregisterFile fails with NotFound when this node has no listing for the file, and
+// otherwise leaves a withdrawal that hides it from CheckHolders.
+func TestUnregisterFile(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	ctx := context.Background()
+	if _, err := s.UnregisterFile(ctx, &market.UnregisterFileRequest{FileHash: testHash}); status.Code(err) != codes.NotFound {
+		t.Fatalf("unregistering a file never registered: %v, want NotFound", err)
+	}
+
+	//registered a minute ago, as a withdrawal in the same second may lose to the listing
+	putOurListing(t, s, &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}, 60)
+	if holders, err := s.CheckHolders(ctx, &market.CheckHoldersRequest{FileHash: testHash}); err != nil || len(holders.GetHolders()) != 1 {
+		t.Fatalf("CheckHolders found %d holders before the withdrawal (%v), want ours", len(holders.GetHolders()), err)
+	}
+
+	if _, err := s.UnregisterFile(ctx, &market.UnregisterFileRequest{FileHash: testHash}); err != nil {
+		t.Fatal(err)
+	}
+	entry := ourEntry(t, s, storedChain(t, s, validator.MarketPrefix+testHash))
+	if entry == nil || !entry.Withdrawn() {
+		t.Fatalf("the chain holds %v in place of our withdrawal", entry)
+	}
+	holders, err := s.CheckHolders(ctx, &market.CheckHoldersRequest{FileHash: testHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders.GetHolders()) != 0 {
+		t.Errorf("CheckHolders found %d holders after the withdrawal, want none", len(holders.GetHolders()))
+	}
+}
+
+// A withdrawal that cannot be put leaves the listing tracked, so it is still renewed.
+func TestUnregisterFileRestoresTracking(t *testing.T) {
+	s := newTestServer(t, rejectWithdrawals{validator.NewOrcaNamespaces()})
+	s.Republisher = market.NewRepublisher(s, time.Hour)
+	ctx := context.Background()
+	if _, err := s.RegisterFile(ctx, &market.RegisterFileRequest{FileHash: testHash, Ttl: 600, Keywords: []string{"music"}, User: &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.UnregisterFile(ctx, &market.UnregisterFileRequest{FileHash: testHash}); err == nil {
+		t.Fatal("the withdrawal was put")
+	}
+	tracked := s.Republisher.Untrack(testHash)
+	if tracked == nil || tracked.GetTtl() != 600 || !slices.Equal(tracked.GetKeywords(), []string{"music"}) {
+		t.Errorf("after the failed withdrawal the republisher tracks %v, want the registration", tracked)
+	}
+}
+
+// A rating with the longest comment allowed still fits in a chain entry.
+func TestRateProducerLongestComment(t *testing.T) {
+	s := newTestServer(t, validator.NewOrcaNamespaces())
+	_, producer, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := util.MarshalUserID(producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comment := strings.Repeat("a", record.MaxCommentLength)
+	if _, err := s.RateProducer(context.Background(), &market.RateProducerRequest{Producer: id, Score: 4, Comment: comment}); err != nil {
+		t.Fatal(err)
+	}
+	reputation, err := s.GetReputation(context.Background(), &market.GetReputationRequest{Producer: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reputation.GetRatings()) != 1 || reputation.GetRatings()[0].GetComment() != comment {
+		t.Errorf("the producer's ratings are %d, want ours with its comment", len(reputation.GetRatings()))
+	}
+}
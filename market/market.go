@@ -4,31 +4,76 @@
 *		https://ldej.nl/post/building-an-echo-application-with-libp2p/
 *		https://github.com/libp2p/go-libp2p/blob/master/examples/chat-with-rendezvous/chat.go
 *		https://github.com/libp2p/go-libp2p/blob/master/examples/pubsub/basic-chat-with-rendezvous/main.go
-*/
+ */
 
 package market
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/proto"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
-	record "github.com/libp2p/go-libp2p-record"
+	p2precord "github.com/libp2p/go-libp2p-record"
 	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
-	"github.com/golang/protobuf/proto"
+	"log"
+	"orcanet/record"
+	"orcanet/util"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// Lifetime of a record when the registering client does not ask for one.
+	DefaultEntryTTL = 24 * time.Hour
+	// Upper bound on writing a merged chain back to the DHT.
+	mergePutTimeout = time.Minute
+	// Most merged chains written back to the DHT at once, further ones are skipped until
+	// a later lookup finds them again.
+	maxWriteBacks = 32
+)
+
+// Validator for holder chains that can also merge conflicting copies of a chain.
+type ChainValidator interface {
+	p2precord.Validator
+	Merge(key string, values [][]byte) ([]byte, error)
+}
+
 type Server struct {
 	UnimplementedMarketServer
-	K_DHT *dht.IpfsDHT
-	PrivKey crypto.PrivKey
-	PubKey crypto.PubKey
-	V record.Validator
+	K_DHT       *dht.IpfsDHT
+	PrivKey     crypto.PrivKey
+	PubKey      crypto.PubKey
+	V           ChainValidator
+	Republisher *Republisher
+	// How often WatchHolders looks the chain up again, DefaultWatchInterval if zero
+	WatchInterval time.Duration
+	// How many files of a batch are worked on at once, DefaultBatchParallelism if zero
+	BatchParallelism int
+	// Probes holders for CheckHolders requests that ask for it, probing is disabled if nil
+	Prober *Prober
+	// Announces registrations on the announce topic, announcing is disabled if nil
+	Announcer *Announcer
+	// Where RegisterFile stores listings, ChainStorage if unset
+	Storage StorageMode
+	// Keeps this node's registrations across restarts, nothing is kept if nil
+	Registry *Registry
+	// DHT keys whose merged chain is being written back, and how many there are
+	writingBack sync.Map
+	writeBacks  atomic.Int32
 }
 
 /*
- * gRPC service to register a file on the DHT market.
- * 
+ * gRPC service to register a file on the DHT market. Any keywords given are added to the
+ * keyword index used by Search, and the registration is announced to subscribers of the
+ * announce topic.
+ *
  * Parameters:
  *   ctx: Context
  *   in: A protobuf RegisterFileRequest struct that represents the file/producer being registered.
@@ -39,120 +84,784 @@ type Server struct {
  * Author: Austin
  */
 func (s *Server) RegisterFile(ctx context.Context, in *RegisterFileRequest) (*emptypb.Empty, error) {
-	hash := in.GetFileHash()
-	pubKeyBytes, err := s.PubKey.Raw()
-	if(err != nil){
+	if in.GetUser() == nil {
+		return nil, status.Error(codes.InvalidArgument, "user is required")
+	}
+	ttl := in.GetTtl()
+	if ttl == 0 {
+		ttl = uint32(DefaultEntryTTL.Seconds())
+	}
+	if ttl > uint32(record.MaxEntryTTL.Seconds()) {
+		return nil, status.Errorf(codes.InvalidArgument, "ttl cannot exceed %d seconds", uint32(record.MaxEntryTTL.Seconds()))
+	}
+
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	keywords, err := normalizeKeywords(in.GetKeywords())
+	if err != nil {
+		return nil, err
+	}
+	//once the listing is put the registration has taken effect, so the index is checked first
+	err = s.checkIndexLimits(ctx, hash, keywords)
+	if err != nil {
 		return nil, err
 	}
-	in.GetUser().Id = pubKeyBytes;
 
-	value, err := s.K_DHT.GetValue(ctx, "orcanet/market/" + hash);
-	if(err != nil){
-		value = make([]byte, 0)
+	signed, err := s.putListing(ctx, hash, in.GetUser(), ttl)
+	if err != nil {
+		return nil, err
 	}
+	s.announce(ctx, hash, signed)
 
-	//remove record for id if it already exists
-	for i := 0; i < len(value) - 8; i++ {
-		messageLength := uint16(value[i + 1]) << 8 | uint16(value[i])
-		digitalSignatureLength := uint16(value[i + 3]) << 8 | uint16(value[i + 2])
-		contentLength := messageLength + digitalSignatureLength
-		user := &User{}
+	if s.Republisher != nil {
+		s.Republisher.Track(hash, in.GetUser(), ttl, keywords)
+	}
+	s.saveRegistration(ctx, hash, in.GetUser(), ttl, keywords)
+	err = s.indexFile(ctx, hash, keywords, true)
+	if err != nil {
+		log.Printf("Failed to index %s under its keywords: %v", hash, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+/*
+ * Sign a record for user with the server's key and merge it into the chain for a file,
+ * replacing any previous record of ours and pruning expired ones.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file being registered
+ *   user: The producer details to register, its id is set to the server's public key
+ *   ttl: Seconds the record stays valid for
+ *   flags: Record flags, e.g. record.FlagWithdrawn
+ *
+ * Returns:
+ *   The signed record
+ *   An error, if any
+ */
+func (s *Server) putRecord(ctx context.Context, hash string, user *User, ttl uint32, flags byte) (*SignedEntry, error) {
+	signed, err := SignEntry(s.PrivKey, hash, user, ttl, flags)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.mergeEntry(ctx, hash, signed.toRecord(), user.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
 
-		err := proto.Unmarshal(value[i + 4:i + 4 + int(messageLength)], user) //will parse bytes only until user struct is filled out
+/*
+ * gRPC service to register a file with an entry the client signed with its own key. The
+ * server checks the entry like the validator would and merges it into the chain for the
+ * file without re-signing it, so the listing is attributed to the client's key. The entry
+ * must be signed for the normalized file hash, see util.NormalizeFileHash.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf RegisterSignedEntryRequest struct holding the file hash and signed entry.
+ *
+ * Returns:
+ *   An empty protobuf struct
+ *   An InvalidArgument status if the entry is not valid for the file, has expired or is
+ *   withdrawn, a FailedPrecondition status if the chain already holds a newer entry for
+ *   the same key, or any other error
+ */
+func (s *Server) RegisterSignedEntry(ctx context.Context, in *RegisterSignedEntryRequest) (*emptypb.Empty, error) {
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	signed := in.GetEntry()
+	if signed.GetFlags() > 0xFF {
+		return nil, status.Error(codes.InvalidArgument, "entry flags must fit in a byte")
+	}
+	entry := signed.toRecord()
+
+	//check the entry on its own, exactly as the validator will check the merged chain
+	value, err := record.Encode(&record.Chain{Entries: []record.Entry{entry}})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid entry: %v", err)
+	}
+	if err := s.V.Validate(chainKey(hash), value); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid entry for file %s: %v", hash, err)
+	}
+	//the validator accepts expired entries so chains holding them stay valid, but a new one is of no use
+	if entry.Expired(uint64(time.Now().UTC().Unix())) {
+		return nil, status.Errorf(codes.InvalidArgument, "entry for file %s has expired", hash)
+	}
+	if entry.Withdrawn() {
+		return nil, status.Errorf(codes.InvalidArgument, "entry for file %s is withdrawn, it registers nothing", hash)
+	}
+
+	user := &User{}
+	if err := proto.Unmarshal(entry.Message, user); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid entry: %v", err)
+	}
+	id, err := util.CanonicalUserID(user.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid entry: %v", err)
+	}
+
+	superseded, err := s.mergeEntry(ctx, hash, entry, id)
+	if err != nil {
+		return nil, err
+	}
+	if superseded {
+		return nil, status.Errorf(codes.FailedPrecondition, "the chain for file %s already holds a newer entry for this key", hash)
+	}
+	s.announce(ctx, hash, signed)
+	return &emptypb.Empty{}, nil
+}
+
+/*
+ * Sign an entry registering user as a holder of a file. Go clients can use this to build
+ * the entry for RegisterSignedEntry with their own key.
+ *
+ * Parameters:
+ *   privKey: The key to sign with, user's id is set to its public key
+ *   hash: The hash of the file being registered, normalized before it is signed
+ *   user: The producer details to register
+ *   ttl: Seconds the entry stays valid for
+ *   flags: Record flags, e.g. record.FlagWithdrawn
+ *
+ * Returns:
+ *   The signed entry, registered at the current time
+ *   An error, if any
+ */
+func SignEntry(privKey crypto.PrivKey, hash string, user *User, ttl uint32, flags byte) (*SignedEntry, error) {
+	hash, err := util.NormalizeFileHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	pubKeyBytes, err := util.MarshalUserID(privKey.GetPublic())
+	if err != nil {
+		return nil, err
+	}
+	user.Id = pubKeyBytes
+
+	userProtoBytes, err := proto.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	entry := record.Entry{
+		RegisteredAt: uint64(time.Now().UTC().Unix()),
+		TTL:          ttl,
+		Flags:        flags,
+		Message:      userProtoBytes,
+	}
+	//the registration time, ttl and flags are signed along with the user message
+	entry.Signature, err = privKey.Sign(entry.SigningPayload(hash))
+	if err != nil {
+		return nil, err
+	}
+	return &SignedEntry{
+		User:         entry.Message,
+		RegisteredAt: entry.RegisteredAt,
+		Ttl:          entry.TTL,
+		Flags:        uint32(entry.Flags),
+		Signature:    entry.Signature,
+	}, nil
+}
+
+// Convert a signed entry from the gRPC API into a chain record.
+func (e *SignedEntry) toRecord() record.Entry {
+	return record.Entry{
+		RegisteredAt: e.GetRegisteredAt(),
+		TTL:          e.GetTtl(),
+		Flags:        byte(e.GetFlags()),
+		Message:      e.GetUser(),
+		Signature:    e.GetSignature(),
+	}
+}
+
+/*
+ * Merge a signed entry into the chain for a file, replacing any older entry for the same
+ * key and pruning expired ones. Once the chain holds record.ShardThreshold live entries
+ * besides ours it is full, and the entry goes into the shard its key belongs in instead,
+ * see record.Shard, which keeps its record.MaxShardEntries newest entries. The full chain is put back along with the shard, pruned but still
+ * full, so it does not age out of the DHT while readers still need it to find the shards:
+ * it keeps every live entry it held and is filled up from the shards, so the DHT selects
+ * it over the stored copy.
+ * When enough entries have expired that it is no longer full, the live entries of the
+ * shards are taken back into it and new entries go to it again.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file
+ *   entry: The signed entry to add
+ *   id: The canonical id of the key that signed the entry
+ *
+ * Returns:
+ *   True, without putting anything, if the chain already holds a newer entry for the key
+ *   An error, if any
+ */
+func (s *Server) mergeEntry(ctx context.Context, hash string, entry record.Entry, id []byte) (bool, error) {
+	key := chainKey(hash)
+	chain, err := s.getMergedChain(ctx, key)
+	if errors.Is(err, routing.ErrNotFound) {
+		chain = &record.Chain{}
+	} else if err != nil {
+		return false, err
+	}
+	//readers only look up the shards of a chain stored full, so nothing is in them otherwise
+	if len(chain.Entries) < record.ShardThreshold {
+		return s.addEntry(ctx, key, chain, entry, id, holderID, 0)
+	}
+
+	//a shard that could not be looked up may hold records, so the chain is not rewritten
+	//without it
+	shards, errs := s.getShards(ctx, hash)
+	for i := range errs {
+		if errs[i] != nil && !errors.Is(errs[i], routing.ErrNotFound) {
+			return false, fmt.Errorf("%s: %w", shardKey(hash, i), errs[i])
+		}
+	}
+	combined, err := combineChains(append([]*record.Chain{chain}, shards...))
+	if err != nil {
+		return false, err
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	live := 0
+	for i := range combined.Entries {
+		ownerID, err := holderID(&combined.Entries[i])
 		if err != nil {
-			return nil, err
+			return false, err
+		}
+		if combined.Entries[i].Expired(now) {
+			continue
+		}
+		if !bytes.Equal(ownerID, id) {
+			live++
+		} else if combined.Entries[i].RegisteredAt > entry.RegisteredAt {
+			return true, nil
 		}
+	}
+	if live < record.ShardThreshold {
+		return s.addEntry(ctx, key, combined, entry, id, holderID, 0)
+	}
 
-		if(len(user.GetId()) == len(in.GetUser().GetId())){
-			recordExists := true
-			for i := range in.GetUser().GetId() {
-				if user.GetId()[i] != in.GetUser().GetId()[i] {
-					recordExists = false
-					break
-				}
-			}
+	shard := record.Shard(id)
+	superseded, err := s.addEntry(ctx, shardKey(hash, shard), shards[shard], entry, id, holderID, record.MaxShardEntries)
+	if err != nil || superseded {
+		return superseded, err
+	}
 
-			if(recordExists){
-				value = append(value[:i], value[i + 4 + int(contentLength):]...);
-				break;
+	//our entry is in the shard now. The chain keeps every live record it holds, which
+	//combined lists first, so the DHT selects it over the stored copy, and is filled up
+	//from the shards; ours stays in it, in its new version, only if it was there
+	inChain := false
+	for i := range chain.Entries {
+		ownerID, err := holderID(&chain.Entries[i])
+		if err != nil {
+			return false, err
+		}
+		inChain = inChain || bytes.Equal(ownerID, id)
+	}
+	refreshed := &record.Chain{Entries: make([]record.Entry, 0, record.ShardThreshold)}
+	for i := 0; i < len(combined.Entries) && len(refreshed.Entries) < record.ShardThreshold; i++ {
+		candidate := combined.Entries[i]
+		if candidate.Expired(now) {
+			continue
+		}
+		ownerID, err := holderID(&candidate)
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(ownerID, id) {
+			if !inChain {
+				continue
 			}
+			candidate = entry
 		}
+		refreshed.Entries = append(refreshed.Entries, candidate)
+	}
+	value, err := record.Encode(refreshed)
+	if err != nil {
+		return false, err
+	}
+	if err := s.K_DHT.PutValue(ctx, key, value); err != nil {
+		return false, fmt.Errorf("refreshing the full chain for %s: %w", hash, err)
+	}
+	return false, nil
+}
 
-		i = i + 4 + int(contentLength) - 1;
+/*
+ * Merge a signed entry into any kind of chain, like mergeEntry does for holder chains.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   key: The DHT key of the chain
+ *   entry: The signed entry to add
+ *   id: The canonical id of the key that signed the entry
+ *   entryID: Finds the canonical id of the public key an entry of the chain belongs to
+ *   max: Most entries the chain may hold, only the newest are kept, or 0 for no limit
+ *
+ * Returns:
+ *   True, without putting anything, if the chain already holds a newer entry for the key
+ *   An error, if any
+ */
+func (s *Server) mergeChainEntry(ctx context.Context, key string, entry record.Entry, id []byte, entryID func(*record.Entry) ([]byte, error), max int) (bool, error) {
+	//only a chain that does not exist yet may be started over, putting our entry alone
+	//after a failed lookup would overwrite every other entry on the peers that take it
+	chain, err := s.getMergedChain(ctx, key)
+	if errors.Is(err, routing.ErrNotFound) {
+		chain = &record.Chain{}
+	} else if err != nil {
+		return false, err
 	}
+	return s.addEntry(ctx, key, chain, entry, id, entryID, max)
+}
 
-	record := make([]byte, 0);
-	userProtoBytes, err := proto.Marshal(in.GetUser());
-	if(err != nil){
-		return nil, err
+// Put a chain that was looked up under key back with entry in place of any older entry
+// for id, keeping at most max entries unless it is 0, as mergeChainEntry does.
+func (s *Server) addEntry(ctx context.Context, key string, chain *record.Chain, entry record.Entry, id []byte, entryID func(*record.Entry) ([]byte, error), max int) (bool, error) {
+	//remove record for id if it already exists, along with any expired records
+	previous, err := removeEntry(chain, id, uint64(time.Now().UTC().Unix()), entryID)
+	if err != nil {
+		return false, err
 	}
-	userProtoSize := len(userProtoBytes);
-	signature, err := s.PrivKey.Sign(userProtoBytes);
-	if(err != nil){
-		return nil, err
+	if previous != nil && previous.RegisteredAt > entry.RegisteredAt {
+		return true, nil
 	}
-	signatureLength := len(signature);
-	record = append(record, byte(userProtoSize));
-	record = append(record, byte(userProtoSize >> 8));
-	record = append(record, byte(signatureLength));
-	record = append(record, byte(signatureLength >> 8));
-	record = append(record, userProtoBytes...);
-	record = append(record, signature...);
-
-	currentTime := time.Now().UTC()
-	unixTimestamp := currentTime.Unix()
-	unixTimestampInt64 := uint64(unixTimestamp)
-	for i := 7; i >= 0; i-- {
-		curByte := unixTimestampInt64 >> (i * 8)
-		record = append(record, byte(curByte))
+	chain.Entries = append(chain.Entries, entry)
+	if max > 0 {
+		chain.KeepNewest(max)
 	}
 
-	if(len(value) != 0){
-		value = value[:len(value) - 8] //get rid of previous values timestamp
+	value, err := record.Encode(chain)
+	if err != nil {
+		return false, err
 	}
-	value = append(value, record...);
+	return false, s.K_DHT.PutValue(ctx, key, value)
+}
 
-	err = s.K_DHT.PutValue(ctx, "orcanet/market/" + in.GetFileHash(), value);
-	if(err != nil){
-		return nil, err;
+/*
+ * Sign an entry of a reputation or metadata chain with the server's key.
+ *
+ * Parameters:
+ *   domain: The signature domain of the kind of chain, e.g. record.MetadataSignatureDomain
+ *   subject: The DHT key of the chain without its prefix
+ *   message: The marshalled message of the entry
+ *   ttl: How long the entry stays valid for
+ *
+ * Returns:
+ *   The signed entry, registered at the current time
+ *   An error, if any
+ */
+func (s *Server) signEntry(domain string, subject string, message []byte, ttl time.Duration) (record.Entry, error) {
+	entry := record.Entry{
+		RegisteredAt: uint64(time.Now().UTC().Unix()),
+		TTL:          uint32(ttl.Seconds()),
+		Message:      message,
 	}
-	return &emptypb.Empty{}, nil
+	var err error
+	entry.Signature, err = s.PrivKey.Sign(entry.SigningPayloadWithDomain(domain, subject))
+	return entry, err
 }
 
 /*
  * gRPC service to check for producers who have registered a specific file.
- * 
+ * Records that have outlived their TTL or were withdrawn are left out. The holders can be
+ * filtered by price and age, sorted, and fetched a page at a time.
+ *
  * Parameters:
  *   ctx: Context
  *   in: A protobuf CheckHoldersRequest struct that represents the file to look up.
  *
  * Returns:
- *   A HoldersResponse protobuf struct that represents the producers and their prices.
- *   An error, if any
+ *   A HoldersResponse protobuf struct that represents the producers and their prices, empty
+ *   if nobody has registered the file.
+ *   An InvalidArgument status if the file hash or page token is malformed, or the error of
+ *   a failed lookup
  * Author: Austin
  */
 func (s *Server) CheckHolders(ctx context.Context, in *CheckHoldersRequest) (*HoldersResponse, error) {
-	hash := in.GetFileHash()
-	users := make([]*User, 0)
-	value, err := s.K_DHT.GetValue(ctx, "orcanet/market/" + hash);
-	if(err != nil){
-		return &HoldersResponse{Holders: users}, nil
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodePageToken(in.GetPageToken(), in.GetSort())
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	//a file nobody has registered has no holders, any other failure is the caller's to see
+	holders, err := s.fileHolders(ctx, hash, now)
+	if err != nil {
+		return nil, err
+	}
+	holders = filterHolders(holders, in, now)
+	sortHolders(holders, in.GetSort())
+	page, next := paginate(holders, in.GetSort(), after, in.GetLimit())
+
+	users := make([]*User, 0, len(page))
+	for _, h := range page {
+		users = append(users, h.user)
+	}
+	response := &HoldersResponse{Holders: users, NextPageToken: next}
+	if in.GetWithReputation() || in.GetProbe() || in.GetWithCost() {
+		response.Details = s.holderDetails(ctx, hash, users, in)
 	}
+	return response, nil
+}
+
+// A live holder of a file, as listed in its chain.
+type holder struct {
+	user *User
+	// Canonical id of the holder's public key
+	id           []byte
+	registeredAt uint64
+}
+
+/*
+ * List the holders in a chain, leaving out records that were withdrawn or have expired.
+ *
+ * Parameters:
+ *   chain: The chain for a file
+ *   now: The current unix time, used to find expired records
+ *
+ * Returns:
+ *   The holders of the file
+ *   An error, if any user message or id could not be parsed
+ */
+func liveHolders(chain *record.Chain, now uint64) ([]holder, error) {
+	holders := make([]holder, 0)
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		if entry.Withdrawn() || entry.Expired(now) {
+			continue
+		}
 
-	for i := 0; i < len(value) - 8; i++ {
-		messageLength := uint16(value[i + 1]) << 8 | uint16(value[i])
-		digitalSignatureLength := uint16(value[i + 3]) << 8 | uint16(value[i + 2])
-		contentLength := messageLength + digitalSignatureLength
 		user := &User{}
+		err := proto.Unmarshal(entry.Message, user)
+		if err != nil {
+			return nil, err
+		}
+		id, err := util.CanonicalUserID(user.GetId())
+		if err != nil {
+			return nil, err
+		}
+		holders = append(holders, holder{user: user, id: id, registeredAt: entry.RegisteredAt})
+	}
+	return holders, nil
+}
+
+/*
+ * gRPC service to withdraw this node's listing for a file from the DHT market.
+ * Our record in the chain is replaced with a withdrawn record, which shadows the old
+ * record wherever copies of the chain are merged until it expires. It also hides any
+ * provider record of ours for the file, which cannot be taken back. The registration is
+ * only forgotten once the withdrawn record is put, so a failed call leaves it in place.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf UnregisterFileRequest struct that represents the file to withdraw.
+ *
+ * Returns:
+ *   An empty protobuf struct
+ *   A NotFound status if this node neither has a record for the file nor registered it,
+ *   or any other error
+ */
+func (s *Server) UnregisterFile(ctx context.Context, in *UnregisterFileRequest) (*emptypb.Empty, error) {
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	pubKeyBytes, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	id, err := util.CanonicalUserID(pubKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	//stop renewing the listing, waiting out a republish already in flight so it cannot land
+	//after the withdrawal, and renew it again if the withdrawal fails
+	var tracked *Registration
+	if s.Republisher != nil {
+		tracked = s.Republisher.Untrack(hash)
+	}
+	restore := func() {
+		if tracked != nil {
+			s.Republisher.Track(hash, tracked.GetUser(), tracked.GetTtl(), tracked.GetKeywords())
+		}
+	}
+
+	//we may be listed in the chain or with a provider record, a withdrawal in the chain covers both
+	holders, err := s.fileHolders(ctx, hash, uint64(time.Now().UTC().Unix()))
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	listed := false
+	for _, h := range holders {
+		listed = listed || bytes.Equal(h.id, id)
+	}
+	//a registration the lookup missed may still be live on peers it did not reach
+	if !listed && tracked == nil {
+		return nil, status.Errorf(codes.NotFound, "no record registered by this node for file %s", hash)
+	}
+
+	//the withdrawn record must outlive any record of ours it shadows
+	_, err = s.putRecord(ctx, hash, &User{}, uint32(record.MaxEntryTTL.Seconds()), record.FlagWithdrawn)
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	if s.Registry != nil {
+		if err := s.Registry.Delete(ctx, hash); err != nil {
+			log.Printf("Failed to delete the registration of %s: %v", hash, err)
+		}
+	}
+
+	//a stale index entry only costs searches a lookup, so the listing counts as withdrawn
+	if err := s.indexFile(ctx, hash, tracked.GetKeywords(), false); err != nil {
+		log.Printf("Failed to remove %s from the keyword index: %v", hash, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+/*
+ * Look up the chain for a file. Only if the chain is stored full are its shards looked up,
+ * concurrently, and combined with it. Every copy of each found on the DHT is merged, and
+ * if that yields more than the best single copy the merged chain is written back so
+ * replicas converge.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file
+ *
+ * Returns:
+ *   The chain, combined with whichever shards could be looked up if it is full
+ *   routing.ErrNotFound if no valid chain was found, or the error of a failed lookup
+ */
+func (s *Server) getChain(ctx context.Context, hash string) (*record.Chain, error) {
+	chain, err := s.getMergedChain(ctx, chainKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	if len(chain.Entries) < record.ShardThreshold {
+		return chain, nil
+	}
+
+	shards, errs := s.getShards(ctx, hash)
+	found := []*record.Chain{chain}
+	for i := range shards {
+		if errs[i] == nil {
+			found = append(found, shards[i])
+		} else if !errors.Is(errs[i], routing.ErrNotFound) {
+			log.Printf("Failed to look up %s: %v", shardKey(hash, i), errs[i])
+		}
+	}
+	return combineChains(found)
+}
 
-		err := proto.Unmarshal(value[i + 4:i + 4 + int(messageLength)], user) //will parse bytes only until user struct is filled out
+// Look up every shard of the holder chain for a file concurrently, along with the error
+// of each lookup. A shard that is not on the DHT is returned empty.
+func (s *Server) getShards(ctx context.Context, hash string) ([]*record.Chain, []error) {
+	shards := make([]*record.Chain, record.ShardCount)
+	errs := make([]error, record.ShardCount)
+	s.forEachConcurrently(record.ShardCount, func(i int) {
+		shards[i], errs[i] = s.getMergedChain(ctx, shardKey(hash, i))
+		if shards[i] == nil {
+			shards[i] = &record.Chain{}
+		}
+	})
+	return shards, errs
+}
+
+/*
+ * Combine the shards of a holder chain into one chain holding the newest record of every
+ * public key, as merging copies of a single chain would.
+ *
+ * Parameters:
+ *   chains: The full chain, along with its shards
+ *
+ * Returns:
+ *   The combined chain
+ *   An error, if the user message or id of a record could not be parsed
+ */
+func combineChains(chains []*record.Chain) (*record.Chain, error) {
+	newest := make(map[string]*record.Entry)
+	order := make([]string, 0)
+	for _, chain := range chains {
+		for i := range chain.Entries {
+			entry := &chain.Entries[i]
+			id, err := holderID(entry)
+			if err != nil {
+				return nil, err
+			}
+			current, ok := newest[string(id)]
+			if !ok {
+				order = append(order, string(id))
+			}
+			if !ok || entry.RegisteredAt > current.RegisteredAt ||
+				(entry.RegisteredAt == current.RegisteredAt && bytes.Compare(entry.Signature, current.Signature) > 0) {
+				newest[string(id)] = entry
+			}
+		}
+	}
+
+	combined := &record.Chain{Entries: make([]record.Entry, 0, len(order))}
+	for _, id := range order {
+		combined.Entries = append(combined.Entries, *newest[id])
+	}
+	return combined, nil
+}
+
+// The DHT key of the holder chain for a file.
+func chainKey(hash string) string {
+	return "orcanet/market/" + hash
+}
+
+// The DHT key of a shard of the holder chain for a file.
+func shardKey(hash string, shard int) string {
+	return fmt.Sprintf("orcanet/market/%s/%d", hash, shard)
+}
+
+/*
+ * Look up the chain stored under a DHT key, merging every copy found like getChain.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   key: The DHT key of the chain
+ *
+ * Returns:
+ *   The merged chain
+ *   An error, if no valid chain was found
+ */
+func (s *Server) getMergedChain(ctx context.Context, key string) (*record.Chain, error) {
+	results, err := s.K_DHT.SearchValue(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, 0)
+	for value := range results {
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, routing.ErrNotFound
+	}
+
+	best := values[len(values)-1]
+	merged, err := s.V.Merge(key, values)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(merged, best) {
+		s.writeBack(key, merged)
+	}
+	return record.Decode(merged)
+}
+
+/*
+ * Write a merged chain back to the DHT in the background. A key already being written
+ * back is skipped, as is every key once maxWriteBacks are in flight, so lookups cannot
+ * pile up puts; the next lookup of a skipped key writes it back instead.
+ *
+ * Parameters:
+ *   key: The DHT key of the chain
+ *   merged: The merged chain
+ */
+func (s *Server) writeBack(key string, merged []byte) {
+	if _, busy := s.writingBack.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	if s.writeBacks.Add(1) > maxWriteBacks {
+		s.writeBacks.Add(-1)
+		s.writingBack.Delete(key)
+		return
+	}
+	go func() {
+		defer s.writingBack.Delete(key)
+		defer s.writeBacks.Add(-1)
+		putCtx, cancel := context.WithTimeout(context.Background(), mergePutTimeout)
+		defer cancel()
+		if err := s.K_DHT.PutValue(putCtx, key, merged); err != nil {
+			log.Printf("Failed to write back merged chain for %s: %v", key, err)
+		}
+	}()
+}
+
+/*
+ * Remove the record belonging to a public key from a chain. Records that have expired
+ * are pruned as well.
+ *
+ * Parameters:
+ *   chain: The chain to remove records from
+ *   id: Bytes of the public key whose record should be removed
+ *   now: The current unix time, used to find expired records
+ *
+ * Returns:
+ *   The unexpired record for id that was removed, or nil if there was none
+ *   An error, if any user message could not be parsed
+ */
+func removeRecord(chain *record.Chain, id []byte, now uint64) (*record.Entry, error) {
+	return removeEntry(chain, id, now, holderID)
+}
+
+/*
+ * Remove the entry belonging to a public key from any kind of chain, pruning expired
+ * entries as well.
+ *
+ * Parameters:
+ *   chain: The chain to remove entries from
+ *   id: Canonical id of the public key whose entry should be removed
+ *   now: The current unix time, used to find expired entries
+ *   entryID: Finds the canonical id of the public key an entry belongs to
+ *
+ * Returns:
+ *   The unexpired entry for id that was removed, or nil if there was none
+ *   An error, if the id of any entry could not be found
+ */
+func removeEntry(chain *record.Chain, id []byte, now uint64, entryID func(*record.Entry) ([]byte, error)) (*record.Entry, error) {
+	var previous *record.Entry
+	kept := make([]record.Entry, 0, len(chain.Entries))
+	for _, entry := range chain.Entries {
+		//older RSA records encode the same key differently, so compare canonical ids
+		ownerID, err := entryID(&entry)
 		if err != nil {
 			return nil, err
 		}
+		isOwn := bytes.Equal(ownerID, id)
+		expired := entry.Expired(now)
+		if isOwn && !expired {
+			previous = &entry
+		}
+		if isOwn || expired {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	chain.Entries = kept
+	return previous, nil
+}
 
-		users = append(users, user);
-		i = i + 4 + int(contentLength) - 1
+// The canonical id of the public key of the holder in a holder chain entry.
+func holderID(entry *record.Entry) ([]byte, error) {
+	user := &User{}
+	if err := proto.Unmarshal(entry.Message, user); err != nil {
+		return nil, err
 	}
+	return util.CanonicalUserID(user.GetId())
+}
 
-	return &HoldersResponse{Holders: users}, nil
+// Normalize a file hash given by a client with util.NormalizeFileHash, or return an
+// InvalidArgument status.
+func normalizeFileHash(hash string) (string, error) {
+	normalized, err := util.NormalizeFileHash(hash)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return normalized, nil
 }
@@ -27,6 +27,30 @@ type MarketClient interface {
 	RegisterFile(ctx context.Context, in *RegisterFileRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	// check for holders of a file. returns a list of users
 	CheckHolders(ctx context.Context, in *CheckHoldersRequest, opts ...grpc.CallOption) (*HoldersResponse, error)
+	// remove this node's listing for a file from the market
+	UnregisterFile(ctx context.Context, in *UnregisterFileRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// register a file with an entry the client signed with its own key
+	RegisterSignedEntry(ctx context.Context, in *RegisterSignedEntryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// stream changes to the holders of a file until the client cancels
+	WatchHolders(ctx context.Context, in *CheckHoldersRequest, opts ...grpc.CallOption) (Market_WatchHoldersClient, error)
+	// register many files at once. returns a result for each file, in request order
+	RegisterFiles(ctx context.Context, in *RegisterFilesRequest, opts ...grpc.CallOption) (*RegisterFilesResponse, error)
+	// check for holders of many files at once. returns a result for each file, in request order
+	CheckHoldersBatch(ctx context.Context, in *CheckHoldersBatchRequest, opts ...grpc.CallOption) (*CheckHoldersBatchResponse, error)
+	// rate a producer, replacing any earlier rating of it by this node
+	RateProducer(ctx context.Context, in *RateProducerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// get the aggregated ratings of a producer
+	GetReputation(ctx context.Context, in *GetReputationRequest, opts ...grpc.CallOption) (*Reputation, error)
+	// publish the name, size and type of a file, replacing any earlier metadata published by this node
+	PublishMetadata(ctx context.Context, in *PublishMetadataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// get the metadata published for a file
+	GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*MetadataResponse, error)
+	// find files registered under all of the given keywords, ranked by number of holders
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// stream the registrations announced on the network until the client cancels
+	SubscribeAnnouncements(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Market_SubscribeAnnouncementsClient, error)
+	// list the files this node has registered, as kept in its local registry
+	ListRegistrations(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListRegistrationsResponse, error)
 }
 
 type marketClient struct {
@@ -55,6 +79,160 @@ func (c *marketClient) CheckHolders(ctx context.Context, in *CheckHoldersRequest
 	return out, nil
 }
 
+func (c *marketClient) UnregisterFile(ctx context.Context, in *UnregisterFileRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/market.Market/UnregisterFile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) RegisterSignedEntry(ctx context.Context, in *RegisterSignedEntryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/market.Market/RegisterSignedEntry", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) WatchHolders(ctx context.Context, in *CheckHoldersRequest, opts ...grpc.CallOption) (Market_WatchHoldersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Market_ServiceDesc.Streams[0], "/market.Market/WatchHolders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &marketWatchHoldersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Market_WatchHoldersClient interface {
+	Recv() (*HolderEvent, error)
+	grpc.ClientStream
+}
+
+type marketWatchHoldersClient struct {
+	grpc.ClientStream
+}
+
+func (x *marketWatchHoldersClient) Recv() (*HolderEvent, error) {
+	m := new(HolderEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *marketClient) RegisterFiles(ctx context.Context, in *RegisterFilesRequest, opts ...grpc.CallOption) (*RegisterFilesResponse, error) {
+	out := new(RegisterFilesResponse)
+	err := c.cc.Invoke(ctx, "/market.Market/RegisterFiles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) CheckHoldersBatch(ctx context.Context, in *CheckHoldersBatchRequest, opts ...grpc.CallOption) (*CheckHoldersBatchResponse, error) {
+	out := new(CheckHoldersBatchResponse)
+	err := c.cc.Invoke(ctx, "/market.Market/CheckHoldersBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) RateProducer(ctx context.Context, in *RateProducerRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/market.Market/RateProducer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) GetReputation(ctx context.Context, in *GetReputationRequest, opts ...grpc.CallOption) (*Reputation, error) {
+	out := new(Reputation)
+	err := c.cc.Invoke(ctx, "/market.Market/GetReputation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) PublishMetadata(ctx context.Context, in *PublishMetadataRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/market.Market/PublishMetadata", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) GetMetadata(ctx context.Context, in *GetMetadataRequest, opts ...grpc.CallOption) (*MetadataResponse, error) {
+	out := new(MetadataResponse)
+	err := c.cc.Invoke(ctx, "/market.Market/GetMetadata", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, "/market.Market/Search", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *marketClient) SubscribeAnnouncements(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (Market_SubscribeAnnouncementsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Market_ServiceDesc.Streams[1], "/market.Market/SubscribeAnnouncements", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &marketSubscribeAnnouncementsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Market_SubscribeAnnouncementsClient interface {
+	Recv() (*Announcement, error)
+	grpc.ClientStream
+}
+
+type marketSubscribeAnnouncementsClient struct {
+	grpc.ClientStream
+}
+
+func (x *marketSubscribeAnnouncementsClient) Recv() (*Announcement, error) {
+	m := new(Announcement)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *marketClient) ListRegistrations(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ListRegistrationsResponse, error) {
+	out := new(ListRegistrationsResponse)
+	err := c.cc.Invoke(ctx, "/market.Market/ListRegistrations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MarketServer is the server API for Market service.
 // All implementations must embed UnimplementedMarketServer
 // for forward compatibility
@@ -63,6 +241,30 @@ type MarketServer interface {
 	RegisterFile(context.Context, *RegisterFileRequest) (*emptypb.Empty, error)
 	// check for holders of a file. returns a list of users
 	CheckHolders(context.Context, *CheckHoldersRequest) (*HoldersResponse, error)
+	// remove this node's listing for a file from the market
+	UnregisterFile(context.Context, *UnregisterFileRequest) (*emptypb.Empty, error)
+	// register a file with an entry the client signed with its own key
+	RegisterSignedEntry(context.Context, *RegisterSignedEntryRequest) (*emptypb.Empty, error)
+	// stream changes to the holders of a file until the client cancels
+	WatchHolders(*CheckHoldersRequest, Market_WatchHoldersServer) error
+	// register many files at once. returns a result for each file, in request order
+	RegisterFiles(context.Context, *RegisterFilesRequest) (*RegisterFilesResponse, error)
+	// check for holders of many files at once. returns a result for each file, in request order
+	CheckHoldersBatch(context.Context, *CheckHoldersBatchRequest) (*CheckHoldersBatchResponse, error)
+	// rate a producer, replacing any earlier rating of it by this node
+	RateProducer(context.Context, *RateProducerRequest) (*emptypb.Empty, error)
+	// get the aggregated ratings of a producer
+	GetReputation(context.Context, *GetReputationRequest) (*Reputation, error)
+	// publish the name, size and type of a file, replacing any earlier metadata published by this node
+	PublishMetadata(context.Context, *PublishMetadataRequest) (*emptypb.Empty, error)
+	// get the metadata published for a file
+	GetMetadata(context.Context, *GetMetadataRequest) (*MetadataResponse, error)
+	// find files registered under all of the given keywords, ranked by number of holders
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// stream the registrations announced on the network until the client cancels
+	SubscribeAnnouncements(*emptypb.Empty, Market_SubscribeAnnouncementsServer) error
+	// list the files this node has registered, as kept in its local registry
+	ListRegistrations(context.Context, *emptypb.Empty) (*ListRegistrationsResponse, error)
 	mustEmbedUnimplementedMarketServer()
 }
 
@@ -76,6 +278,42 @@ func (UnimplementedMarketServer) RegisterFile(context.Context, *RegisterFileRequ
 func (UnimplementedMarketServer) CheckHolders(context.Context, *CheckHoldersRequest) (*HoldersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CheckHolders not implemented")
 }
+func (UnimplementedMarketServer) UnregisterFile(context.Context, *UnregisterFileRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnregisterFile not implemented")
+}
+func (UnimplementedMarketServer) RegisterSignedEntry(context.Context, *RegisterSignedEntryRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterSignedEntry not implemented")
+}
+func (UnimplementedMarketServer) WatchHolders(*CheckHoldersRequest, Market_WatchHoldersServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchHolders not implemented")
+}
+func (UnimplementedMarketServer) RegisterFiles(context.Context, *RegisterFilesRequest) (*RegisterFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterFiles not implemented")
+}
+func (UnimplementedMarketServer) CheckHoldersBatch(context.Context, *CheckHoldersBatchRequest) (*CheckHoldersBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckHoldersBatch not implemented")
+}
+func (UnimplementedMarketServer) RateProducer(context.Context, *RateProducerRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RateProducer not implemented")
+}
+func (UnimplementedMarketServer) GetReputation(context.Context, *GetReputationRequest) (*Reputation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReputation not implemented")
+}
+func (UnimplementedMarketServer) PublishMetadata(context.Context, *PublishMetadataRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishMetadata not implemented")
+}
+func (UnimplementedMarketServer) GetMetadata(context.Context, *GetMetadataRequest) (*MetadataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetadata not implemented")
+}
+func (UnimplementedMarketServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedMarketServer) SubscribeAnnouncements(*emptypb.Empty, Market_SubscribeAnnouncementsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeAnnouncements not implemented")
+}
+func (UnimplementedMarketServer) ListRegistrations(context.Context, *emptypb.Empty) (*ListRegistrationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRegistrations not implemented")
+}
 func (UnimplementedMarketServer) mustEmbedUnimplementedMarketServer() {}
 
 // UnsafeMarketServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +363,228 @@ func _Market_CheckHolders_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Market_UnregisterFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnregisterFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).UnregisterFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/UnregisterFile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).UnregisterFile(ctx, req.(*UnregisterFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_RegisterSignedEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterSignedEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).RegisterSignedEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/RegisterSignedEntry",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).RegisterSignedEntry(ctx, req.(*RegisterSignedEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_WatchHolders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CheckHoldersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MarketServer).WatchHolders(m, &marketWatchHoldersServer{stream})
+}
+
+type Market_WatchHoldersServer interface {
+	Send(*HolderEvent) error
+	grpc.ServerStream
+}
+
+type marketWatchHoldersServer struct {
+	grpc.ServerStream
+}
+
+func (x *marketWatchHoldersServer) Send(m *HolderEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Market_RegisterFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).RegisterFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/RegisterFiles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).RegisterFiles(ctx, req.(*RegisterFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_CheckHoldersBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckHoldersBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).CheckHoldersBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/CheckHoldersBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).CheckHoldersBatch(ctx, req.(*CheckHoldersBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_RateProducer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RateProducerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).RateProducer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/RateProducer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).RateProducer(ctx, req.(*RateProducerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_GetReputation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReputationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).GetReputation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/GetReputation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).GetReputation(ctx, req.(*GetReputationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_PublishMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).PublishMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/PublishMetadata",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).PublishMetadata(ctx, req.(*PublishMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_GetMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).GetMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/GetMetadata",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).GetMetadata(ctx, req.(*GetMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/Search",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Market_SubscribeAnnouncements_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MarketServer).SubscribeAnnouncements(m, &marketSubscribeAnnouncementsServer{stream})
+}
+
+type Market_SubscribeAnnouncementsServer interface {
+	Send(*Announcement) error
+	grpc.ServerStream
+}
+
+type marketSubscribeAnnouncementsServer struct {
+	grpc.ServerStream
+}
+
+func (x *marketSubscribeAnnouncementsServer) Send(m *Announcement) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Market_ListRegistrations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MarketServer).ListRegistrations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/market.Market/ListRegistrations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MarketServer).ListRegistrations(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Market_ServiceDesc is the grpc.ServiceDesc for Market service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,7 +600,58 @@ var Market_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CheckHolders",
 			Handler:    _Market_CheckHolders_Handler,
 		},
+		{
+			MethodName: "UnregisterFile",
+			Handler:    _Market_UnregisterFile_Handler,
+		},
+		{
+			MethodName: "RegisterSignedEntry",
+			Handler:    _Market_RegisterSignedEntry_Handler,
+		},
+		{
+			MethodName: "RegisterFiles",
+			Handler:    _Market_RegisterFiles_Handler,
+		},
+		{
+			MethodName: "CheckHoldersBatch",
+			Handler:    _Market_CheckHoldersBatch_Handler,
+		},
+		{
+			MethodName: "RateProducer",
+			Handler:    _Market_RateProducer_Handler,
+		},
+		{
+			MethodName: "GetReputation",
+			Handler:    _Market_GetReputation_Handler,
+		},
+		{
+			MethodName: "PublishMetadata",
+			Handler:    _Market_PublishMetadata_Handler,
+		},
+		{
+			MethodName: "GetMetadata",
+			Handler:    _Market_GetMetadata_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _Market_Search_Handler,
+		},
+		{
+			MethodName: "ListRegistrations",
+			Handler:    _Market_ListRegistrations_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchHolders",
+			Handler:       _Market_WatchHolders_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAnnouncements",
+			Handler:       _Market_SubscribeAnnouncements_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "market/market.proto",
 }
@@ -0,0 +1,166 @@
+package market
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// An unsigned holder chain entry for a user, which is all combineChains looks at.
+func testEntry(t *testing.T, id []byte, registeredAt uint64, signature byte) record.Entry {
+	t.Helper()
+	message, err := proto.Marshal(&User{Id: id, Name: "test", Ip: "127.0.0.1", Port: 4000, Price: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record.Entry{RegisteredAt: registeredAt, TTL: 3600, Message: message, Signature: []byte{signature}}
+}
+
+// A new user id of an Ed25519 key.
+func testUserID(t *testing.T) []byte {
+	t.Helper()
+	_, pubKey, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := util.MarshalUserID(pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// A full chain and its shards combine into the newest entry of every holder.
+func TestCombineChains(t *testing.T) {
+	a, b, c := testUserID(t), testUserID(t), testUserID(t)
+	tests := []struct {
+		name   string
+		chains []*record.Chain
+		want   []record.Entry
+	}{
+		{
+			name:   "disjoint",
+			chains: []*record.Chain{{Entries: []record.Entry{testEntry(t, a, 10, 1)}}, {Entries: []record.Entry{testEntry(t, b, 10, 1)}}},
+			want:   []record.Entry{testEntry(t, a, 10, 1), testEntry(t, b, 10, 1)},
+		},
+		{
+			name:   "newest wins",
+			chains: []*record.Chain{{Entries: []record.Entry{testEntry(t, a, 10, 9), testEntry(t, c, 5, 1)}}, {Entries: []record.Entry{testEntry(t, a, 20, 1)}}},
+			want:   []record.Entry{testEntry(t, a, 20, 1), testEntry(t, c, 5, 1)},
+		},
+		{
+			name:   "older entry of a later chain loses",
+			chains: []*record.Chain{{Entries: []record.Entry{testEntry(t, a, 20, 1)}}, {Entries: []record.Entry{testEntry(t, a, 10, 9)}}},
+			want:   []record.Entry{testEntry(t, a, 20, 1)},
+		},
+		{
+			name:   "tie goes to the greater signature",
+			chains: []*record.Chain{{Entries: []record.Entry{testEntry(t, a, 10, 2)}}, {Entries: []record.Entry{testEntry(t, a, 10, 7)}}, {Entries: []record.Entry{testEntry(t, a, 10, 3)}}},
+			want:   []record.Entry{testEntry(t, a, 10, 7)},
+		},
+		{
+			name:   "empty",
+			chains: []*record.Chain{{}, {}},
+			want:   []record.Entry{},
+		},
+	}
+	for _, test := range tests {
+		combined, err := combineChains(test.chains)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(combined.Entries) != len(test.want) {
+			t.Fatalf("%s: got %d entries, want %d", test.name, len(combined.Entries), len(test.want))
+		}
+		for i := range test.want {
+			got, want := combined.Entries[i], test.want[i]
+			if got.RegisteredAt != want.RegisteredAt || !bytes.Equal(got.Signature, want.Signature) || !bytes.Equal(got.Message, want.Message) {
+				t.Errorf("%s: entry %d is registered at %d with signature %x, want %d with %x", test.name, i, got.RegisteredAt, got.Signature, want.RegisteredAt, want.Signature)
+			}
+		}
+	}
+
+	_, err := combineChains([]*record.Chain{{Entries: []record.Entry{{Message: []byte{0xff}}}}})
+	if err == nil {
+		t.Error("combineChains accepted an entry whose message does not parse")
+	}
+}
+
+// A registration without a user is refused instead of reaching SignEntry.
+func TestRegisterFileRequiresUser(t *testing.T) {
+	s := &Server{}
+	_, err := s.RegisterFile(context.Background(), &RegisterFileRequest{FileHash: "QmTest"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("RegisterFile without a user returned %v, want InvalidArgument", err)
+	}
+}
+
+// Ratings that could not be stored are refused before anything is signed.
+func TestRateProducerRejectsInvalidRatings(t *testing.T) {
+	s := &Server{}
+	producer := testUserID(t)
+	tests := []struct {
+		name    string
+		request *RateProducerRequest
+	}{
+		{"no producer", &RateProducerRequest{Score: 3}},
+		{"score too low", &RateProducerRequest{Producer: producer, Score: record.MinRatingScore - 1}},
+		{"score too high", &RateProducerRequest{Producer: producer, Score: record.MaxRatingScore + 1}},
+		{"comment too long", &RateProducerRequest{Producer: producer, Score: 3, Comment: strings.Repeat("a", record.MaxCommentLength+1)}},
+	}
+	for _, test := range tests {
+		_, err := s.RateProducer(context.Background(), test.request)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: RateProducer returned %v, want InvalidArgument", test.name, err)
+		}
+	}
+}
+
+// Total prices round up to whole mb, and a total that does not fit in an int64 is refused.
+func TestTotalPrice(t *testing.T) {
+	const mb = bytesPerMB
+	tests := []struct {
+		price int64
+		size  uint64
+		want  int64
+		ok    bool
+	}{
+		{10, 0, 0, true},
+		{10, 1, 10, true},
+		{10, mb, 10, true},
+		{10, mb + 1, 20, true},
+		{-10, 2 * mb, -20, true},
+		{math.MaxInt64, mb, math.MaxInt64, true},
+		{math.MinInt64, mb, math.MinInt64, true},
+		{math.MaxInt64, mb + 1, 0, false},
+		{math.MinInt64, mb + 1, 0, false},
+		{1 << 20, math.MaxUint64, 0, false},
+		{1 << 43, 1 << 40, 0, false},
+		{1 << 42, 1 << 40, 1 << 62, true},
+		{-(1 << 43), 1 << 40, math.MinInt64, true},
+		{-(1 << 43), 1<<40 + mb, 0, false},
+	}
+	for _, test := range tests {
+		got, err := totalPrice(test.price, test.size)
+		if (err == nil) != test.ok {
+			t.Errorf("totalPrice(%d, %d) error = %v, want ok %v", test.price, test.size, err, test.ok)
+			continue
+		}
+		if !test.ok && status.Code(err) != codes.InvalidArgument {
+			t.Errorf("totalPrice(%d, %d) error = %v, want InvalidArgument", test.price, test.size, err)
+		}
+		if got != test.want {
+			t.Errorf("totalPrice(%d, %d) = %d, want %d", test.price, test.size, got, test.want)
+		}
+	}
+}
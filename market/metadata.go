@@ -0,0 +1,245 @@
+package market
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"math/bits"
+	"slices"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+const (
+	// Lifetime of published metadata. The publisher's node puts it back on the DHT until
+	// it lapses, see TrackRecord, and it has to be published again to outlive it.
+	DefaultMetadataTTL = 30 * 24 * time.Hour
+	// Bytes in the mb that holder prices are quoted per.
+	bytesPerMB = 1 << 20
+)
+
+/*
+ * gRPC service to publish what a file is. The metadata is signed with the server's key
+ * and merged into the file's metadata chain under orcanet/meta/<hash>, replacing any
+ * metadata this node published for the file before. It is republished until it expires.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf PublishMetadataRequest struct holding the file hash and its metadata.
+ *
+ * Returns:
+ *   An empty protobuf struct
+ *   An InvalidArgument status if the metadata is not valid, or any other error
+ */
+func (s *Server) PublishMetadata(ctx context.Context, in *PublishMetadataRequest) (*emptypb.Empty, error) {
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	publisherID, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	message, err := proto.Marshal(&FileMetadata{
+		Publisher: publisherID,
+		Name:      in.GetName(),
+		Size:      in.GetSize(),
+		MimeType:  in.GetMimeType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.signEntry(record.MetadataSignatureDomain, hash, message, DefaultMetadataTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	//check the entry before merging it, so a bad name or type is reported to the client
+	key := metadataKey(hash)
+	value, err := record.Encode(&record.Chain{Entries: []record.Entry{entry}})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid metadata: %v", err)
+	}
+	if err := s.V.Validate(key, value); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid metadata: %v", err)
+	}
+
+	if _, err := s.mergeChainEntry(ctx, key, entry, publisherID, metadataPublisherID, record.MaxMetadataPublishers); err != nil {
+		return nil, err
+	}
+	s.keepRecord(ctx, key, entry)
+	return &emptypb.Empty{}, nil
+}
+
+/*
+ * gRPC service to get what the publishers of a file say it is.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf GetMetadataRequest struct holding the file hash.
+ *
+ * Returns:
+ *   The metadata most publishers agree on along with every claim, empty if none was published
+ *   An InvalidArgument status if the file hash is malformed, or any other error
+ */
+func (s *Server) GetMetadata(ctx context.Context, in *GetMetadataRequest) (*MetadataResponse, error) {
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.metadataClaims(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &MetadataResponse{Metadata: agreedMetadata(claims), Claims: claims}, nil
+}
+
+/*
+ * Look up the unexpired metadata published for a file.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the file
+ *
+ * Returns:
+ *   The metadata of each publisher, from oldest to newest, empty if none was published
+ *   An error, if the lookup failed
+ */
+func (s *Server) metadataClaims(ctx context.Context, hash string) ([]*FileMetadata, error) {
+	claims := make([]*FileMetadata, 0)
+	chain, err := s.getMergedChain(ctx, metadataKey(hash))
+	if errors.Is(err, routing.ErrNotFound) {
+		return claims, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	entries := make([]*record.Entry, 0, len(chain.Entries))
+	for i := range chain.Entries {
+		if !chain.Entries[i].Expired(now) {
+			entries = append(entries, &chain.Entries[i])
+		}
+	}
+	//oldest first, so later claims win ties in agreedMetadata
+	slices.SortStableFunc(entries, func(a, b *record.Entry) int {
+		return cmp.Compare(a.RegisteredAt, b.RegisteredAt)
+	})
+	for _, entry := range entries {
+		metadata := &FileMetadata{}
+		if err := proto.Unmarshal(entry.Message, metadata); err != nil {
+			return nil, err
+		}
+		claims = append(claims, metadata)
+	}
+	return claims, nil
+}
+
+/*
+ * Pick the metadata to report for a file. The size of a file is the same wherever it is
+ * stored, so the size claimed by the most publishers is trusted, and the newest claim of
+ * that size is returned. Ties between sizes go to the size claimed most recently.
+ *
+ * Parameters:
+ *   claims: The metadata of each publisher, from oldest to newest
+ *
+ * Returns:
+ *   The agreed metadata, nil if there are no claims
+ */
+func agreedMetadata(claims []*FileMetadata) *FileMetadata {
+	votes := make(map[uint64]int)
+	for _, claim := range claims {
+		votes[claim.GetSize()]++
+	}
+	var agreed *FileMetadata
+	for _, claim := range claims {
+		if agreed == nil || votes[claim.GetSize()] >= votes[agreed.GetSize()] {
+			agreed = claim
+		}
+	}
+	return agreed
+}
+
+/*
+ * Find the size of a file as seen by a holder: the size the holder published itself if
+ * it did, otherwise the agreed size.
+ *
+ * Parameters:
+ *   claims: The metadata of each publisher
+ *   agreed: The agreed metadata, may be nil
+ *   holder: The holder
+ *
+ * Returns:
+ *   The size of the file in bytes
+ *   False if the size is not known
+ */
+func holderFileSize(claims []*FileMetadata, agreed *FileMetadata, holder *User) (uint64, bool) {
+	holderID, err := util.CanonicalUserID(holder.GetId())
+	if err == nil {
+		for _, claim := range claims {
+			publisherID, err := util.CanonicalUserID(claim.GetPublisher())
+			if err == nil && bytes.Equal(publisherID, holderID) {
+				return claim.GetSize(), true
+			}
+		}
+	}
+	if agreed == nil {
+		return 0, false
+	}
+	return agreed.GetSize(), true
+}
+
+/*
+ * Work out the price of a whole file at a price per mb, charging for the last partial mb.
+ *
+ * Parameters:
+ *   pricePerMB: The holder's price
+ *   size: The size of the file in bytes
+ *
+ * Returns:
+ *   The price
+ *   An InvalidArgument status if the price does not fit in an int64
+ */
+func totalPrice(pricePerMB int64, size uint64) (int64, error) {
+	mbs := size / bytesPerMB
+	if size%bytesPerMB != 0 {
+		mbs++
+	}
+	//multiply the magnitudes, a negative price may reach one further than a positive one
+	magnitude, limit := uint64(pricePerMB), uint64(math.MaxInt64)
+	if pricePerMB < 0 {
+		magnitude, limit = -magnitude, limit+1
+	}
+	hi, lo := bits.Mul64(magnitude, mbs)
+	if hi != 0 || lo > limit {
+		return 0, status.Errorf(codes.InvalidArgument, "price of %d per mb for %d mb overflows", pricePerMB, mbs)
+	}
+	if pricePerMB < 0 {
+		return -int64(lo), nil
+	}
+	return int64(lo), nil
+}
+
+// The DHT key of a file's metadata chain.
+func metadataKey(hash string) string {
+	return "orcanet/meta/" + hash
+}
+
+// The canonical id of the public key of the publisher in a metadata chain entry.
+func metadataPublisherID(entry *record.Entry) ([]byte, error) {
+	metadata := &FileMetadata{}
+	if err := proto.Unmarshal(entry.Message, metadata); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(metadata.GetPublisher())
+}
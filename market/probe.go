@@ -0,0 +1,171 @@
+package market
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// How long a probe waits for a holder to accept a connection.
+	DefaultProbeTimeout = 2 * time.Second
+	// How long the result of a probe is reused for.
+	DefaultProbeCacheTTL = 30 * time.Second
+	// Most endpoints the cache holds. Expired results are swept once it is full, and if it
+	// is still full an arbitrary result is dropped.
+	maxProbeCacheSize = 1024
+)
+
+// A probe result along with when it was measured.
+type probeResult struct {
+	liveness *Liveness
+	at       time.Time
+}
+
+// Checks whether holders are reachable at the ip and port they advertise by opening a TCP
+// connection to them. Results are cached per endpoint so repeated lookups of popular
+// files do not dial the same holders over and over. Anyone can advertise any endpoint, so
+// only public addresses are dialed unless AllowPrivate is set, and the market server
+// cannot be used to scan its own host or network.
+type Prober struct {
+	// Also dial loopback, private and link-local addresses, for networks run on a LAN
+	AllowPrivate bool
+
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]probeResult
+}
+
+/*
+ * Create a prober.
+ *
+ * Parameters:
+ *   timeout: How long to wait for a holder to accept a connection
+ *   cacheTTL: How long a result is reused for, results are not cached if zero
+ *
+ * Returns:
+ *   A prober
+ */
+func NewProber(timeout time.Duration, cacheTTL time.Duration) *Prober {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	return &Prober{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]probeResult),
+	}
+}
+
+/*
+ * Dial a holder's advertised endpoint, or reuse a recent result for it. A holder that
+ * advertises a host name is dialed at an address it resolves to, and is reported
+ * unreachable if any address it resolves to may not be dialed.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   user: The holder to probe
+ *
+ * Returns:
+ *   Whether the holder accepted a connection and how long it took
+ */
+func (p *Prober) Probe(ctx context.Context, user *User) *Liveness {
+	if user.GetPort() <= 0 || user.GetPort() > 65535 || user.GetIp() == "" {
+		return &Liveness{Reachable: false}
+	}
+	endpoint := net.JoinHostPort(user.GetIp(), strconv.Itoa(int(user.GetPort())))
+
+	now := time.Now()
+	p.mu.Lock()
+	cached, ok := p.cache[endpoint]
+	p.mu.Unlock()
+	if ok && now.Sub(cached.at) < p.cacheTTL {
+		return cached.liveness
+	}
+
+	liveness := &Liveness{Reachable: false}
+	if ip, ok := p.resolve(ctx, user.GetIp()); ok {
+		liveness = p.dial(ctx, net.JoinHostPort(ip.String(), strconv.Itoa(int(user.GetPort()))))
+	}
+	//a probe cut short by the caller says nothing about the holder
+	if ctx.Err() != nil {
+		return liveness
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.cache[endpoint]; !ok && len(p.cache) >= maxProbeCacheSize {
+		for key, result := range p.cache {
+			if now.Sub(result.at) >= p.cacheTTL {
+				delete(p.cache, key)
+			}
+		}
+		for key := range p.cache {
+			if len(p.cache) < maxProbeCacheSize {
+				break
+			}
+			delete(p.cache, key)
+		}
+	}
+	if p.cacheTTL > 0 {
+		p.cache[endpoint] = probeResult{liveness: liveness, at: now}
+	}
+	return liveness
+}
+
+/*
+ * Find the address to dial a holder at.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   host: The ip or host name the holder advertises
+ *
+ * Returns:
+ *   The address
+ *   False if the host does not resolve, or resolves to an address that may not be dialed
+ */
+func (p *Prober) resolve(ctx context.Context, host string) (net.IP, bool) {
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return nil, false
+		}
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !p.AllowPrivate && !publicIP(ip) {
+			return nil, false
+		}
+	}
+	return ips[0], true
+}
+
+// Whether an address is routable on the internet, rather than loopback, private,
+// link-local, unspecified or multicast.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// Open and close a TCP connection to an endpoint, timing how long it takes.
+func (p *Prober) dial(ctx context.Context, endpoint string) *Liveness {
+	dialer := net.Dialer{Timeout: p.timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return &Liveness{Reachable: false}
+	}
+	rtt := time.Since(start)
+	conn.Close()
+	return &Liveness{Reachable: true, Rtt: durationpb.New(rtt)}
+}
@@ -0,0 +1,64 @@
+package market
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+// Only public addresses are dialed unless the prober allows private ones.
+func TestProberResolve(t *testing.T) {
+	tests := []struct {
+		host    string
+		public  bool
+		private bool
+	}{
+		{"203.0.113.7", true, true},
+		{"2001:db8::1", true, true},
+		{"127.0.0.1", false, true},
+		{"::1", false, true},
+		{"localhost", false, true},
+		{"10.1.2.3", false, true},
+		{"192.168.0.1", false, true},
+		{"172.16.0.1", false, true},
+		{"169.254.169.254", false, true},
+		{"fe80::1", false, true},
+		{"fd00::1", false, true},
+		{"::ffff:127.0.0.1", false, true},
+		{"0.0.0.0", false, true},
+		{"224.0.0.1", false, true},
+		{"ff02::1", false, true},
+	}
+	prober := NewProber(0, 0)
+	for _, test := range tests {
+		for _, allowPrivate := range []bool{false, true} {
+			prober.AllowPrivate = allowPrivate
+			want := test.public
+			if allowPrivate {
+				want = test.private
+			}
+			if _, ok := prober.resolve(context.Background(), test.host); ok != want {
+				t.Errorf("resolve(%q) with AllowPrivate %v = %v, want %v", test.host, allowPrivate, ok, want)
+			}
+		}
+	}
+}
+
+// A private endpoint that accepts connections is only reported reachable if allowed.
+func TestProbePrivate(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	holder := &User{Ip: "127.0.0.1", Port: int32(listener.Addr().(*net.TCPAddr).Port)}
+
+	if NewProber(0, 0).Probe(context.Background(), holder).GetReachable() {
+		t.Error("a loopback endpoint was dialed")
+	}
+	allowed := NewProber(0, 0)
+	allowed.AllowPrivate = true
+	if !allowed.Probe(context.Background(), holder).GetReachable() {
+		t.Error("a loopback endpoint was not dialed with AllowPrivate set")
+	}
+}
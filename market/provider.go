@@ -0,0 +1,285 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// Where RegisterFile stores this node's listings. Lookups read holder chains in either
+// mode, and provider records only in provider mode.
+type StorageMode int
+
+const (
+	// Listings are entries of the holder chain stored under orcanet/market/<hash>.
+	ChainStorage StorageMode = iota
+	// Listings are DHT provider records for the file's CID, with the details the holder
+	// lists the file with in its profile under orcanet/profile/<peer>/<hash>. Popular
+	// files then cost every holder a small record of its own instead of a rewrite of the
+	// chain.
+	ProviderStorage
+)
+
+// Most providers of a file a lookup collects, so a file with many holders does not cost
+// a profile lookup for every one of them.
+const maxProviders = 256
+
+/*
+ * Parse the name of a storage mode, as given on the command line.
+ *
+ * Parameters:
+ *   name: "chain" or "provider"
+ *
+ * Returns:
+ *   The storage mode
+ *   An error, if the name is not known
+ */
+func ParseStorageMode(name string) (StorageMode, error) {
+	switch name {
+	case "chain":
+		return ChainStorage, nil
+	case "provider":
+		return ProviderStorage, nil
+	}
+	return ChainStorage, fmt.Errorf("unknown storage mode %q, expected chain or provider", name)
+}
+
+/*
+ * Store this node's listing for a file in the server's storage mode.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file being registered
+ *   user: The producer details to register, its id is set to the server's public key
+ *   ttl: Seconds the listing stays valid for. In provider mode the profile expires
+ *        after it, however long the DHT keeps the provider record.
+ *
+ * Returns:
+ *   A chain entry for the listing, signed with the server's key, which is what was put
+ *   in chain mode
+ *   An error, if any
+ */
+func (s *Server) putListing(ctx context.Context, hash string, user *User, ttl uint32) (*SignedEntry, error) {
+	if s.Storage != ProviderStorage {
+		return s.putRecord(ctx, hash, user, ttl, 0)
+	}
+	signed, err := SignEntry(s.PrivKey, hash, user, ttl, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.provideFile(ctx, hash, user, ttl); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+/*
+ * List this node as a holder of a file with a provider record, putting its profile for
+ * the file first so the record never points at a missing profile.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file being registered
+ *   user: The producer details to register, with its id set to the server's public key
+ *   ttl: Seconds the profile, and a chain entry superseding an earlier withdrawal, stay
+ *        valid for
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (s *Server) provideFile(ctx context.Context, hash string, user *User, ttl uint32) error {
+	fileCID, err := util.FileCID(hash)
+	if err != nil {
+		return err
+	}
+	pid, err := peer.IDFromPublicKey(s.PubKey)
+	if err != nil {
+		return err
+	}
+
+	message, err := proto.Marshal(user)
+	if err != nil {
+		return err
+	}
+	entry, err := s.signEntry(record.ProfileSignatureDomain, profileSubject(pid, hash), message, time.Duration(ttl)*time.Second)
+	if err != nil {
+		return err
+	}
+	value, err := record.Encode(&record.Chain{Entries: []record.Entry{entry}})
+	if err != nil {
+		return err
+	}
+	if err := s.K_DHT.PutValue(ctx, profileKey(pid, hash), value); err != nil {
+		return err
+	}
+
+	//a withdrawal in the chain hides our provider record, so replace it if we withdrew before
+	chain, err := s.getChain(ctx, hash)
+	if err != nil && !errors.Is(err, routing.ErrNotFound) {
+		return err
+	}
+	if err == nil {
+		previous, err := removeRecord(chain, user.GetId(), uint64(time.Now().UTC().Unix()))
+		if err != nil {
+			return err
+		}
+		if previous != nil && previous.Withdrawn() {
+			if _, err := s.putRecord(ctx, hash, proto.Clone(user).(*User), ttl, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.K_DHT.Provide(ctx, fileCID, true)
+}
+
+/*
+ * Look up the live holders of a file listed in its chain and, in provider mode, with
+ * provider records. A holder listed both ways is taken from the chain, and a provider
+ * whose latest entry in the chain is a withdrawal is left out, since provider records
+ * cannot be taken back.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file
+ *   now: The current unix time, used to find expired records
+ *
+ * Returns:
+ *   The holders of the file, empty if nobody has registered it
+ *   An error, if the chain lookup failed
+ */
+func (s *Server) fileHolders(ctx context.Context, hash string, now uint64) ([]holder, error) {
+	chain, err := s.getChain(ctx, hash)
+	if errors.Is(err, routing.ErrNotFound) {
+		chain = &record.Chain{}
+	} else if err != nil {
+		return nil, err
+	}
+	holders, err := liveHolders(chain, now)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool)
+	for _, h := range holders {
+		listed[string(h.id)] = true
+	}
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		if !entry.Withdrawn() || entry.Expired(now) {
+			continue
+		}
+		id, err := holderID(entry)
+		if err != nil {
+			return nil, err
+		}
+		listed[string(id)] = true
+	}
+
+	if s.Storage != ProviderStorage {
+		return holders, nil
+	}
+	for _, h := range s.providerHolders(ctx, hash, now) {
+		if !listed[string(h.id)] {
+			holders = append(holders, h)
+		}
+	}
+	return holders, nil
+}
+
+/*
+ * Look up the holders of a file that list it with provider records, along with their
+ * profiles. At most maxProviders providers are collected, and their profiles are looked
+ * up as they are found, up to BatchParallelism at once. Providers without a live profile
+ * are left out.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file
+ *   now: The current unix time, used to find expired profiles
+ *
+ * Returns:
+ *   The holders found
+ */
+func (s *Server) providerHolders(ctx context.Context, hash string, now uint64) []holder {
+	fileCID, err := util.FileCID(hash)
+	if err != nil {
+		return nil
+	}
+	limit := s.BatchParallelism
+	if limit <= 0 {
+		limit = DefaultBatchParallelism
+	}
+
+	var mu sync.Mutex
+	holders := make([]holder, 0)
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for provider := range s.K_DHT.FindProvidersAsync(ctx, fileCID, maxProviders) {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			h, err := s.profile(ctx, provider.ID, hash, now)
+			if err != nil {
+				if !errors.Is(err, routing.ErrNotFound) && ctx.Err() == nil {
+					log.Printf("Failed to look up the profile of %s: %v", provider.ID, err)
+				}
+				return
+			}
+			mu.Lock()
+			holders = append(holders, *h)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return holders
+}
+
+/*
+ * Look up the profile a peer lists a file with.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   pid: The peer ID of the holder
+ *   hash: The hash of the file
+ *   now: The current unix time, used to find an expired profile
+ *
+ * Returns:
+ *   The holder, as registered at the time of its profile
+ *   routing.ErrNotFound if the peer has no live profile, or any other error
+ */
+func (s *Server) profile(ctx context.Context, pid peer.ID, hash string, now uint64) (*holder, error) {
+	chain, err := s.getMergedChain(ctx, profileKey(pid, hash))
+	if err != nil {
+		return nil, err
+	}
+	holders, err := liveHolders(chain, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(holders) == 0 {
+		return nil, routing.ErrNotFound
+	}
+	return &holders[0], nil
+}
+
+// The DHT key the profile a peer lists a file with is stored under.
+func profileKey(pid peer.ID, hash string) string {
+	return "orcanet/profile/" + profileSubject(pid, hash)
+}
+
+// The part of a profile key after its prefix, which the profile's signature covers.
+func profileSubject(pid peer.ID, hash string) string {
+	return pid.String() + "/" + hash
+}
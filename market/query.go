@@ -0,0 +1,160 @@
+package market
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/binary"
+	"slices"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Where a holder falls in the sort order of a CheckHolders response. Page tokens hold the
+// position of the last holder of a page, so a page starts after it even if holders were
+// added or removed in the meantime.
+type position struct {
+	price        int64
+	registeredAt uint64
+	id           []byte
+}
+
+// The position of a holder.
+func (h *holder) position() position {
+	return position{price: h.user.GetPrice(), registeredAt: h.registeredAt, id: h.id}
+}
+
+/*
+ * Compare two positions in a sort order. Ties are broken on the public key so every
+ * holder has a distinct position.
+ *
+ * Returns:
+ *   A negative number if a comes first, a positive number if b comes first, 0 if equal
+ */
+func comparePositions(a position, b position, order CheckHoldersRequest_SortOrder) int {
+	var c int
+	switch order {
+	case CheckHoldersRequest_PRICE:
+		c = cmp.Compare(a.price, b.price)
+	case CheckHoldersRequest_RECENCY:
+		c = cmp.Compare(b.registeredAt, a.registeredAt)
+	}
+	if c != 0 {
+		return c
+	}
+	return bytes.Compare(a.id, b.id)
+}
+
+/*
+ * Drop the holders that do not pass the price and age filters of a request.
+ *
+ * Parameters:
+ *   holders: The live holders of a file, modified in place
+ *   in: The request holding the filters
+ *   now: The current unix time
+ *
+ * Returns:
+ *   The holders that pass
+ */
+func filterHolders(holders []holder, in *CheckHoldersRequest, now uint64) []holder {
+	kept := holders[:0]
+	for _, h := range holders {
+		if in.MaxPrice != nil && h.user.GetPrice() > in.GetMaxPrice() {
+			continue
+		}
+		if in.GetMaxAge() != 0 && h.registeredAt+uint64(in.GetMaxAge()) < now {
+			continue
+		}
+		kept = append(kept, h)
+	}
+	return kept
+}
+
+// Sort holders in the requested order.
+func sortHolders(holders []holder, order CheckHoldersRequest_SortOrder) {
+	slices.SortFunc(holders, func(a, b holder) int {
+		return comparePositions(a.position(), b.position(), order)
+	})
+}
+
+/*
+ * Cut a page out of sorted holders.
+ *
+ * Parameters:
+ *   holders: The holders, sorted in order
+ *   order: The sort order of the holders
+ *   after: The position the page starts after, nil for the first page
+ *   limit: The most holders in the page, no limit if 0
+ *
+ * Returns:
+ *   The holders in the page
+ *   A token for the next page, empty if this is the last page
+ */
+func paginate(holders []holder, order CheckHoldersRequest_SortOrder, after *position, limit uint32) ([]holder, string) {
+	start := 0
+	if after != nil {
+		start, _ = slices.BinarySearchFunc(holders, *after, func(h holder, p position) int {
+			return comparePositions(h.position(), p, order)
+		})
+		//the holder at the token's position was already returned
+		if start < len(holders) && comparePositions(holders[start].position(), *after, order) == 0 {
+			start++
+		}
+	}
+
+	page := holders[start:]
+	if limit == 0 || uint32(len(page)) <= limit {
+		return page, ""
+	}
+	page = page[:limit]
+	return page, encodePageToken(page[len(page)-1].position(), order)
+}
+
+// Page tokens are the sort order, a sort key and the public key, in URL safe base64.
+func encodePageToken(p position, order CheckHoldersRequest_SortOrder) string {
+	token := []byte{byte(order)}
+	switch order {
+	case CheckHoldersRequest_PRICE:
+		token = binary.BigEndian.AppendUint64(token, uint64(p.price))
+	case CheckHoldersRequest_RECENCY:
+		token = binary.BigEndian.AppendUint64(token, p.registeredAt)
+	}
+	token = append(token, p.id...)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+/*
+ * Decode a page token from a CheckHolders request.
+ *
+ * Parameters:
+ *   token: The page token, may be empty
+ *   order: The sort order of the request, which must match the one the token was made for
+ *
+ * Returns:
+ *   The position the page starts after, nil if there is no token
+ *   An InvalidArgument status if the token is malformed or was made for another sort order
+ */
+func decodePageToken(token string, order CheckHoldersRequest_SortOrder) (*position, error) {
+	if token == "" {
+		return nil, nil
+	}
+	invalid := status.Error(codes.InvalidArgument, "invalid page token")
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) == 0 || raw[0] != byte(order) {
+		return nil, invalid
+	}
+
+	p := &position{}
+	raw = raw[1:]
+	if order == CheckHoldersRequest_PRICE || order == CheckHoldersRequest_RECENCY {
+		if len(raw) < 8 {
+			return nil, invalid
+		}
+		p.price = int64(binary.BigEndian.Uint64(raw))
+		p.registeredAt = binary.BigEndian.Uint64(raw)
+		raw = raw[8:]
+	}
+	p.id = raw
+	return p, nil
+}
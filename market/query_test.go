@@ -0,0 +1,171 @@
+package market
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A holder with a one byte id, which is all paging looks at besides price and time.
+func testHolder(id byte, price int64, registeredAt uint64) holder {
+	return holder{user: &User{Price: price}, id: []byte{id}, registeredAt: registeredAt}
+}
+
+// Page through holders with the given limit, calling change before every page after the
+// first, as holders come and go between requests. Returns the ids in the order returned.
+func pageThrough(t *testing.T, holders []holder, order CheckHoldersRequest_SortOrder, limit uint32, change func(page int, holders []holder) []holder) []byte {
+	t.Helper()
+	seen := make([]byte, 0)
+	token := ""
+	for page := 0; ; page++ {
+		if page > 0 {
+			holders = change(page, holders)
+		}
+		after, err := decodePageToken(token, order)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		sorted := slices.Clone(holders)
+		sortHolders(sorted, order)
+		var result []holder
+		result, token = paginate(sorted, order, after, limit)
+		if limit > 0 && uint32(len(result)) > limit {
+			t.Fatalf("page %d holds %d holders, limit is %d", page, len(result), limit)
+		}
+		for _, h := range result {
+			seen = append(seen, h.id[0])
+		}
+		if token == "" {
+			return seen
+		}
+		if page > len(holders)+1 {
+			t.Fatal("paging does not end")
+		}
+	}
+}
+
+// Pages start after the last holder returned, so holders that stay listed are returned
+// exactly once in order, whatever is added or removed between pages.
+func TestPaginate(t *testing.T) {
+	holders := []holder{
+		testHolder(1, 50, 100),
+		testHolder(2, 10, 400),
+		testHolder(3, 30, 300),
+		testHolder(4, 20, 200),
+		testHolder(5, 40, 500),
+		testHolder(6, 30, 600),
+	}
+	unchanged := func(page int, holders []holder) []holder { return holders }
+	without := func(id byte) func(int, []holder) []holder {
+		return func(page int, holders []holder) []holder {
+			if page != 1 {
+				return holders
+			}
+			return slices.DeleteFunc(slices.Clone(holders), func(h holder) bool { return h.id[0] == id })
+		}
+	}
+	with := func(h holder) func(int, []holder) []holder {
+		return func(page int, holders []holder) []holder {
+			if page != 1 {
+				return holders
+			}
+			return append(slices.Clone(holders), h)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		order  CheckHoldersRequest_SortOrder
+		limit  uint32
+		change func(int, []holder) []holder
+		want   []byte
+	}{
+		//by price, ties broken on the id
+		{"price", CheckHoldersRequest_PRICE, 2, unchanged, []byte{2, 4, 3, 6, 5, 1}},
+		{"price in one page", CheckHoldersRequest_PRICE, 0, unchanged, []byte{2, 4, 3, 6, 5, 1}},
+		{"price with exact pages", CheckHoldersRequest_PRICE, 3, unchanged, []byte{2, 4, 3, 6, 5, 1}},
+		//newest first
+		{"recency", CheckHoldersRequest_RECENCY, 4, unchanged, []byte{6, 5, 2, 3, 4, 1}},
+		{"unsorted", CheckHoldersRequest_UNSORTED, 5, unchanged, []byte{1, 2, 3, 4, 5, 6}},
+		//the last holder of the first page goes away, the next page still starts after it
+		{"removed at cursor", CheckHoldersRequest_PRICE, 2, without(4), []byte{2, 4, 3, 6, 5, 1}},
+		{"removed after cursor", CheckHoldersRequest_PRICE, 2, without(6), []byte{2, 4, 3, 5, 1}},
+		{"removed before cursor", CheckHoldersRequest_PRICE, 2, without(2), []byte{2, 4, 3, 6, 5, 1}},
+		//a holder added before the cursor is not returned, one added after it is
+		{"inserted before cursor", CheckHoldersRequest_PRICE, 2, with(testHolder(7, 5, 700)), []byte{2, 4, 3, 6, 5, 1}},
+		{"inserted after cursor", CheckHoldersRequest_PRICE, 2, with(testHolder(7, 35, 700)), []byte{2, 4, 3, 6, 7, 5, 1}},
+		{"inserted at cursor price", CheckHoldersRequest_PRICE, 2, with(testHolder(0, 20, 700)), []byte{2, 4, 3, 6, 5, 1}},
+		{"inserted newest", CheckHoldersRequest_RECENCY, 2, with(testHolder(7, 1, 700)), []byte{6, 5, 2, 3, 4, 1}},
+		{"inserted between", CheckHoldersRequest_RECENCY, 2, with(testHolder(7, 1, 450)), []byte{6, 5, 7, 2, 3, 4, 1}},
+	}
+	for _, test := range tests {
+		got := pageThrough(t, slices.Clone(holders), test.order, test.limit, test.change)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// Page tokens round trip, and are rejected if malformed or made for another sort order.
+func TestPageToken(t *testing.T) {
+	for _, order := range []CheckHoldersRequest_SortOrder{CheckHoldersRequest_UNSORTED, CheckHoldersRequest_PRICE, CheckHoldersRequest_RECENCY} {
+		h := testHolder(9, 42, 1234)
+		p, err := decodePageToken(encodePageToken(h.position(), order), order)
+		if err != nil {
+			t.Fatalf("order %v: %v", order, err)
+		}
+		if comparePositions(*p, h.position(), order) != 0 {
+			t.Errorf("order %v: token decoded to %+v, want %+v", order, *p, h.position())
+		}
+	}
+
+	p, err := decodePageToken("", CheckHoldersRequest_PRICE)
+	if p != nil || err != nil {
+		t.Errorf("empty token decoded to %v, %v", p, err)
+	}
+	h := testHolder(9, 42, 1234)
+	priceToken := encodePageToken(h.position(), CheckHoldersRequest_PRICE)
+	for _, token := range []string{"!", "AQ", priceToken[:4]} {
+		if _, err := decodePageToken(token, CheckHoldersRequest_PRICE); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("token %q: got %v, want InvalidArgument", token, err)
+		}
+	}
+	if _, err := decodePageToken(priceToken, CheckHoldersRequest_RECENCY); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("price token for a recency request: got %v, want InvalidArgument", err)
+	}
+}
+
+// Holders asking more than maxPrice, or registered longer than maxAge ago, are dropped.
+func TestFilterHolders(t *testing.T) {
+	const now = 1000
+	holders := []holder{
+		testHolder(1, 50, now-10),
+		testHolder(2, 10, now-400),
+		testHolder(3, 30, now-100),
+		testHolder(4, 30, now),
+	}
+	price := func(p int64) *int64 { return &p }
+	tests := []struct {
+		name string
+		in   *CheckHoldersRequest
+		want []byte
+	}{
+		{"no filters", &CheckHoldersRequest{}, []byte{1, 2, 3, 4}},
+		{"max price", &CheckHoldersRequest{MaxPrice: price(30)}, []byte{2, 3, 4}},
+		{"max price of zero", &CheckHoldersRequest{MaxPrice: price(0)}, []byte{}},
+		{"max age", &CheckHoldersRequest{MaxAge: 100}, []byte{1, 3, 4}},
+		{"both", &CheckHoldersRequest{MaxPrice: price(40), MaxAge: 100}, []byte{3, 4}},
+	}
+	for _, test := range tests {
+		kept := filterHolders(slices.Clone(holders), test.in, now)
+		got := make([]byte, 0, len(kept))
+		for _, h := range kept {
+			got = append(got, h.id[0])
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package market
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+const (
+	// Prefix of the datastore keys registrations are stored under, followed by the file hash.
+	registrationPrefix = "/registrations"
+	// Prefix of the datastore keys signed entries of reputation and metadata chains are
+	// stored under, followed by the DHT key of the chain.
+	signedRecordPrefix = "/records"
+)
+
+/*
+ * Keeps the files this node has registered on disk, so its listings can be listed,
+ * republished and repaired after a restart. The ratings and metadata it signed are kept
+ * as well, so they are republished until they expire. Every change is written through to the
+ * datastore before it returns, so a crash loses at most the change being made.
+ */
+type Registry struct {
+	datastore ds.Datastore
+	// Serializes writes so MarkPut cannot undo a concurrent Save
+	mu sync.Mutex
+}
+
+/*
+ * Create a registry backed by a datastore. The datastore should sync its writes to disk,
+ * as store.LevelDB does.
+ *
+ * Parameters:
+ *   datastore: Where registrations are kept
+ *
+ * Returns:
+ *   The registry
+ */
+func NewRegistry(datastore ds.Datastore) *Registry {
+	return &Registry{datastore: datastore}
+}
+
+/*
+ * Store a registration, replacing any earlier registration of the same file.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   registration: The registration, with its normalized file hash
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (r *Registry) Save(ctx context.Context, registration *Registration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save(ctx, registration)
+}
+
+func (r *Registry) save(ctx context.Context, registration *Registration) error {
+	value, err := proto.Marshal(registration)
+	if err != nil {
+		return err
+	}
+	return r.datastore.Put(ctx, registrationKey(registration.GetFileHash()), value)
+}
+
+/*
+ * Forget the registration of a file. Forgetting a file that is not registered does nothing.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the file
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (r *Registry) Delete(ctx context.Context, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.datastore.Delete(ctx, registrationKey(hash))
+}
+
+/*
+ * Record that the listing for a file was put on the DHT.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the file
+ *   at: When the put succeeded
+ *
+ * Returns:
+ *   An error, if any. It is not an error if the file is no longer registered.
+ */
+func (r *Registry) MarkPut(ctx context.Context, hash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, err := r.datastore.Get(ctx, registrationKey(hash))
+	if err == ds.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	registration := &Registration{}
+	if err := proto.Unmarshal(value, registration); err != nil {
+		return err
+	}
+	registration.LastPut = uint64(at.UTC().Unix())
+	return r.save(ctx, registration)
+}
+
+/*
+ * List every registration.
+ *
+ * Parameters:
+ *   ctx: Context
+ *
+ * Returns:
+ *   The registrations, sorted by file hash
+ *   An error, if the datastore could not be read or holds a malformed registration
+ */
+func (r *Registry) List(ctx context.Context) ([]*Registration, error) {
+	results, err := r.datastore.Query(ctx, query.Query{Prefix: registrationPrefix})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	registrations := make([]*Registration, 0, len(entries))
+	for _, entry := range entries {
+		registration := &Registration{}
+		if err := proto.Unmarshal(entry.Value, registration); err != nil {
+			return nil, err
+		}
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].GetFileHash() < registrations[j].GetFileHash()
+	})
+	return registrations, nil
+}
+
+/*
+ * Store a signed entry of a reputation or metadata chain, replacing any entry kept for
+ * the same chain.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   key: The DHT key of the chain
+ *   entry: The entry, as it was signed
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (r *Registry) SaveRecord(ctx context.Context, key string, entry record.Entry) error {
+	//kept in the chain format, so it needs no message of its own
+	value, err := record.Encode(&record.Chain{Entries: []record.Entry{entry}})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.datastore.Put(ctx, signedRecordKey(key), value)
+}
+
+/*
+ * Forget a signed entry, unless a different entry has been kept for its chain since.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   key: The DHT key of the chain
+ *   entry: The entry to forget
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (r *Registry) DeleteRecord(ctx context.Context, key string, entry record.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, err := r.datastore.Get(ctx, signedRecordKey(key))
+	if err == ds.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	chain, err := record.Decode(value)
+	if err == nil && len(chain.Entries) == 1 && !bytes.Equal(chain.Entries[0].Signature, entry.Signature) {
+		return nil
+	}
+	return r.datastore.Delete(ctx, signedRecordKey(key))
+}
+
+/*
+ * List every signed entry kept.
+ *
+ * Parameters:
+ *   ctx: Context
+ *
+ * Returns:
+ *   The entries, keyed by the DHT key of their chain
+ *   An error, if the datastore could not be read or holds a malformed entry
+ */
+func (r *Registry) ListRecords(ctx context.Context) (map[string]record.Entry, error) {
+	results, err := r.datastore.Query(ctx, query.Query{Prefix: signedRecordPrefix})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[string]record.Entry, len(entries))
+	for _, entry := range entries {
+		chain, err := record.Decode(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(chain.Entries) != 1 {
+			return nil, fmt.Errorf("%s holds %d entries, expected 1", entry.Key, len(chain.Entries))
+		}
+		records[strings.TrimPrefix(entry.Key, signedRecordPrefix+"/")] = chain.Entries[0]
+	}
+	return records, nil
+}
+
+// The datastore key the signed entry of ours in a chain is stored under.
+func signedRecordKey(key string) ds.Key {
+	return ds.NewKey(signedRecordPrefix).ChildString(key)
+}
+
+// The datastore key the registration of a file is stored under.
+func registrationKey(hash string) ds.Key {
+	return ds.NewKey(registrationPrefix).ChildString(hash)
+}
+
+/*
+ * gRPC service to list the files this node has registered and not unregistered, with the
+ * details they were registered with and when their listing was last put on the DHT.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: An empty protobuf struct
+ *
+ * Returns:
+ *   A ListRegistrationsResponse holding the registrations, sorted by file hash
+ *   An Unavailable status if the server keeps no registry, or any other error
+ */
+func (s *Server) ListRegistrations(ctx context.Context, in *emptypb.Empty) (*ListRegistrationsResponse, error) {
+	if s.Registry == nil {
+		return nil, status.Error(codes.Unavailable, "this server keeps no registry")
+	}
+	registrations, err := s.Registry.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ListRegistrationsResponse{Registrations: registrations}, nil
+}
+
+/*
+ * Bring this node's listings back after a restart. Every registration in the registry is
+ * republished from now on, and any whose listing is missing from the DHT or out of date
+ * is put again right away, up to BatchParallelism at once. A registration whose listing
+ * was withdrawn after it was last put is dropped instead, as the withdrawal may have
+ * succeeded without the registry being updated. Ratings and metadata that have not
+ * expired are put again right away and republished as well. Call it once the DHT has
+ * peers to look listings up on.
+ *
+ * Parameters:
+ *   ctx: Context
+ *
+ * Returns:
+ *   An error, if the registry could not be read. Listings that fail to be put are logged
+ *   and left to the republisher to retry.
+ */
+func (s *Server) Reconcile(ctx context.Context) error {
+	if s.Registry == nil {
+		return nil
+	}
+	registrations, err := s.Registry.List(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		return err
+	}
+	id, err = util.CanonicalUserID(id)
+	if err != nil {
+		return err
+	}
+
+	s.forEachConcurrently(len(registrations), func(i int) {
+		registration := registrations[i]
+		hash := registration.GetFileHash()
+		withdrawn, err := s.withdrawnAt(ctx, hash, id)
+		if err != nil {
+			log.Printf("Failed to look up our withdrawal of %s: %v", hash, err)
+		} else if withdrawn > 0 && withdrawn >= registration.GetLastPut() {
+			if err := s.Registry.Delete(ctx, hash); err != nil {
+				log.Printf("Failed to delete the registration of %s: %v", hash, err)
+			}
+			if err := s.indexFile(ctx, hash, registration.GetKeywords(), false); err != nil {
+				log.Printf("Failed to remove %s from the keyword index: %v", hash, err)
+			}
+			log.Printf("Dropped our withdrawn listing of %s", hash)
+			return
+		}
+
+		if s.Republisher != nil {
+			s.Republisher.Track(hash, registration.GetUser(), registration.GetTtl(), registration.GetKeywords())
+		}
+		listed, err := s.listedAs(ctx, hash, id, registration.GetUser())
+		if err != nil {
+			log.Printf("Failed to look up our listing of %s: %v", hash, err)
+		}
+		if listed {
+			return
+		}
+
+		_, err = s.putListing(ctx, hash, proto.Clone(registration.GetUser()).(*User), registration.GetTtl())
+		if err != nil {
+			log.Printf("Failed to restore our listing of %s: %v", hash, err)
+			return
+		}
+		if err := s.indexFile(ctx, hash, registration.GetKeywords(), true); err != nil {
+			log.Printf("Failed to index %s under its keywords: %v", hash, err)
+		}
+		s.markPut(ctx, hash)
+		log.Printf("Restored our listing of %s", hash)
+	})
+
+	records, err := s.Registry.ListRecords(ctx)
+	if err != nil {
+		return err
+	}
+	now := uint64(time.Now().UTC().Unix())
+	for key, entry := range records {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if entry.Expired(now) {
+			s.forgetRecord(ctx, key, entry)
+			continue
+		}
+		if s.Republisher != nil {
+			s.Republisher.TrackRecord(key, entry)
+		}
+		if _, err := s.putSignedRecord(ctx, key, entry); err != nil {
+			log.Printf("Failed to restore our record under %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+/*
+ * Find when this node withdrew its listing of a file.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the file
+ *   id: The canonical id of this node's public key
+ *
+ * Returns:
+ *   The registration time of our withdrawn record in the chain, or 0 if the chain holds
+ *   none that is live
+ *   An error, if the lookup failed
+ */
+func (s *Server) withdrawnAt(ctx context.Context, hash string, id []byte) (uint64, error) {
+	chain, err := s.getChain(ctx, hash)
+	if errors.Is(err, routing.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	now := uint64(time.Now().UTC().Unix())
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		if !entry.Withdrawn() || entry.Expired(now) {
+			continue
+		}
+		entryID, err := holderID(entry)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(entryID, id) {
+			return entry.RegisteredAt, nil
+		}
+	}
+	return 0, nil
+}
+
+/*
+ * Check whether the DHT lists this node as a holder of a file with the given details.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The normalized hash of the file
+ *   id: The canonical id of this node's public key
+ *   user: The details the file was registered with
+ *
+ * Returns:
+ *   True if a live listing of ours with the same details was found
+ *   An error, if the lookup failed
+ */
+func (s *Server) listedAs(ctx context.Context, hash string, id []byte, user *User) (bool, error) {
+	holders, err := s.fileHolders(ctx, hash, uint64(time.Now().UTC().Unix()))
+	if err != nil {
+		return false, err
+	}
+	for _, h := range holders {
+		if !bytes.Equal(h.id, id) {
+			continue
+		}
+		//the listed user carries our id, which the registered details may not
+		listed := proto.Clone(h.user).(*User)
+		listed.Id = user.GetId()
+		return proto.Equal(listed, user), nil
+	}
+	return false, nil
+}
+
+// Keep a registration in the registry, if the server has one. The listing is already on
+// the DHT, so a failure is only logged.
+func (s *Server) saveRegistration(ctx context.Context, hash string, user *User, ttl uint32, keywords []string) {
+	if s.Registry == nil {
+		return
+	}
+	registration := &Registration{
+		FileHash: hash,
+		User:     user,
+		Ttl:      ttl,
+		Keywords: keywords,
+		LastPut:  uint64(time.Now().UTC().Unix()),
+	}
+	if err := s.Registry.Save(ctx, registration); err != nil {
+		log.Printf("Failed to save the registration of %s: %v", hash, err)
+	}
+}
+
+// Record a successful put of our listing for a file in the registry, if the server has one.
+func (s *Server) markPut(ctx context.Context, hash string) {
+	if s.Registry == nil {
+		return
+	}
+	if err := s.Registry.MarkPut(ctx, hash, time.Now()); err != nil {
+		log.Printf("Failed to update the registration of %s: %v", hash, err)
+	}
+}
+
+// Republish a signed entry of a reputation or metadata chain until it expires and keep it
+// in the registry, if the server has them. The entry is already on the DHT, so a failure
+// is only logged.
+func (s *Server) keepRecord(ctx context.Context, key string, entry record.Entry) {
+	if s.Republisher != nil {
+		s.Republisher.TrackRecord(key, entry)
+	}
+	if s.Registry == nil {
+		return
+	}
+	if err := s.Registry.SaveRecord(ctx, key, entry); err != nil {
+		log.Printf("Failed to save our record under %s: %v", key, err)
+	}
+}
+
+// Remove a signed entry that expired or was replaced from the registry, if the server has one.
+func (s *Server) forgetRecord(ctx context.Context, key string, entry record.Entry) {
+	if s.Registry == nil {
+		return
+	}
+	if err := s.Registry.DeleteRecord(ctx, key, entry); err != nil {
+		log.Printf("Failed to delete our record under %s: %v", key, err)
+	}
+}
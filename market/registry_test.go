@@ -0,0 +1,196 @@
+package market_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	ds "github.com/ipfs/go-datastore"
+	"orcanet/market"
+	"orcanet/record"
+	"orcanet/store"
+	"orcanet/validator"
+)
+
+// Open the LevelDB datastore in dir, closed when the test ends.
+func openLevelDB(t *testing.T, dir string) ds.Batching {
+	t.Helper()
+	datastore, err := store.Open(store.LevelDB, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { datastore.Close() })
+	return datastore
+}
+
+// Registrations and signed entries are still there after the datastore is reopened.
+func TestRegistryRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	datastore := openLevelDB(t, dir)
+	registry := market.NewRegistry(datastore)
+
+	first := &market.Registration{FileHash: "bb", User: &market.User{Name: "b", Price: 2}, Ttl: 600, Keywords: []string{"music"}, LastPut: 100}
+	second := &market.Registration{FileHash: "aa", User: &market.User{Name: "a", Price: 1}, Ttl: 300, LastPut: 100}
+	for _, registration := range []*market.Registration{first, second} {
+		if err := registry.Save(ctx, registration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put := time.Unix(200, 0)
+	if err := registry.MarkPut(ctx, "aa", put); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.MarkPut(ctx, "cc", put); err != nil {
+		t.Errorf("MarkPut of a file never registered: %v", err)
+	}
+	key := "orcanet/reputation/peer"
+	entry := record.Entry{RegisteredAt: 100, TTL: 600, Message: []byte("rating"), Signature: []byte("signed")}
+	if err := registry.SaveRecord(ctx, key, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := datastore.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	registry = market.NewRegistry(openLevelDB(t, dir))
+	registrations, err := registry.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marked := proto.Clone(second).(*market.Registration)
+	marked.LastPut = 200
+	if len(registrations) != 2 || !proto.Equal(registrations[0], marked) || !proto.Equal(registrations[1], first) {
+		t.Errorf("reopened registry lists %v, want %v and %v", registrations, marked, first)
+	}
+	records, err := registry.ListRecords(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := records[key]; len(records) != 1 || !ok || !bytes.Equal(got.Signature, entry.Signature) || got.RegisteredAt != entry.RegisteredAt {
+		t.Errorf("reopened registry keeps records %v, want %s", records, key)
+	}
+
+	//a record is only forgotten if it is still the one kept
+	other := entry
+	other.Signature = []byte("other")
+	if err := registry.DeleteRecord(ctx, key, other); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := registry.ListRecords(ctx); len(records) != 1 {
+		t.Errorf("forgetting a replaced record removed the kept one")
+	}
+	if err := registry.DeleteRecord(ctx, key, entry); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := registry.ListRecords(ctx); len(records) != 0 {
+		t.Errorf("registry keeps %v after forgetting the record", records)
+	}
+
+	if err := registry.Delete(ctx, "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Delete(ctx, "cc"); err != nil {
+		t.Errorf("Delete of a file never registered: %v", err)
+	}
+	registrations, err = registry.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(registrations) != 1 || registrations[0].GetFileHash() != "bb" {
+		t.Errorf("registry lists %v after deleting aa, want only bb", registrations)
+	}
+}
+
+// Reconcile puts back a listing that is missing or differs from the registration, leaves
+// one that matches, and drops a registration whose listing was withdrawn since.
+func TestReconcile(t *testing.T) {
+	user := &market.User{Name: "test", Ip: "203.0.113.7", Port: 4000, Price: 1}
+	repriced := proto.Clone(user).(*market.User)
+	repriced.Price = 2
+	tests := []struct {
+		name string
+		// Puts what the DHT holds before Reconcile runs
+		setup func(t *testing.T, s *market.Server)
+		// The registration's details
+		user *market.User
+		// Whether our listing is put again, or unchanged if not
+		reput     bool
+		withdrawn bool
+	}{
+		{"missing", func(t *testing.T, s *market.Server) {}, user, true, false},
+		{"listed", func(t *testing.T, s *market.Server) { putOurListing(t, s, user, 60) }, user, false, false},
+		{"listed with other details", func(t *testing.T, s *market.Server) { putOurListing(t, s, user, 60) }, repriced, true, false},
+		{"withdrawn", func(t *testing.T, s *market.Server) {
+			putOurListing(t, s, user, 60)
+			if _, err := s.UnregisterFile(context.Background(), &market.UnregisterFileRequest{FileHash: testHash}); err != nil {
+				t.Fatal(err)
+			}
+		}, user, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestServer(t, validator.NewOrcaNamespaces())
+			test.setup(t, s)
+			var before *record.Entry
+			if value, err := s.K_DHT.GetValue(ctx, validator.MarketPrefix+testHash); err == nil {
+				chain, err := record.Decode(value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				before = ourEntry(t, s, chain)
+			}
+
+			//registered, and last put, before anything the setup put
+			lastPut := uint64(time.Now().UTC().Unix()) - 120
+			s.Registry = market.NewRegistry(openLevelDB(t, t.TempDir()))
+			s.Republisher = market.NewRepublisher(s, time.Hour)
+			registration := &market.Registration{FileHash: testHash, User: test.user, Ttl: 600, LastPut: lastPut}
+			if err := s.Registry.Save(ctx, registration); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Reconcile(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			after := ourEntry(t, s, storedChain(t, s, validator.MarketPrefix+testHash))
+			if after == nil || after.Withdrawn() != test.withdrawn {
+				t.Fatalf("the chain holds %v as our record, want withdrawn %v", after, test.withdrawn)
+			}
+			reput := before == nil || !bytes.Equal(after.Signature, before.Signature)
+			if reput != test.reput {
+				t.Errorf("our listing was put again: %v, want %v", reput, test.reput)
+			}
+			if reput {
+				listed := &market.User{}
+				if err := proto.Unmarshal(after.Message, listed); err != nil {
+					t.Fatal(err)
+				}
+				if listed.GetPrice() != test.user.GetPrice() {
+					t.Errorf("the listing put again asks %d, want %d", listed.GetPrice(), test.user.GetPrice())
+				}
+			}
+
+			registrations, err := s.Registry.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tracked := s.Republisher.Untrack(testHash)
+			if test.withdrawn {
+				if len(registrations) != 0 || tracked != nil {
+					t.Errorf("the withdrawn listing is still registered (%v) or tracked (%v)", registrations, tracked)
+				}
+				return
+			}
+			if len(registrations) != 1 || tracked == nil {
+				t.Fatalf("the listing is not registered (%v) or not tracked (%v)", registrations, tracked)
+			}
+			if marked := registrations[0].GetLastPut() > lastPut; marked != test.reput {
+				t.Errorf("the registration was marked as put: %v, want %v", marked, test.reput)
+			}
+		})
+	}
+}
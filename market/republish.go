@@ -0,0 +1,411 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"orcanet/record"
+)
+
+const (
+	// How often a listing is re-asserted when its TTL allows it.
+	DefaultRepublishInterval = 6 * time.Hour
+	// First delay before retrying a failed republish, doubled on every failure.
+	republishRetryDelay = 30 * time.Second
+	// Upper bound on how long a single republish may take.
+	republishTimeout = 2 * time.Minute
+)
+
+// A file this node has registered and keeps alive on the DHT.
+type listing struct {
+	user     *User
+	ttl      uint32
+	keywords []string
+	next     time.Time
+	failures int
+}
+
+// A signed entry of a reputation or metadata chain this node keeps on the DHT. It is put
+// again exactly as it was signed, so it keeps its registration time and still lapses at
+// the end of its TTL.
+type signedRecord struct {
+	entry    record.Entry
+	next     time.Time
+	failures int
+}
+
+// Keeps this node's records alive on the DHT. Kademlia records expire and are lost as
+// nodes churn, so every registered file is periodically re-put, merging our record into
+// whatever chain is stored at the time. Ratings and metadata are re-put the same way
+// until their own TTL runs out.
+type Republisher struct {
+	server   *Server
+	interval time.Duration
+
+	mu       sync.Mutex
+	listings map[string]*listing
+	// Signed entries of reputation and metadata chains, keyed by the DHT key of the chain
+	records map[string]*signedRecord
+	// Closed when the republish in progress for a file is done, keyed by file hash.
+	putting map[string]chan struct{}
+	// Signalled when something is tracked, so the loop does not sleep past its first put.
+	wake chan struct{}
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+/*
+ * Create a republisher that periodically re-puts this node's records for every file
+ * registered through server.
+ *
+ * Parameters:
+ *   server: The market server whose records are republished
+ *   interval: How often each record is re-asserted, at most half of its TTL
+ *
+ * Returns:
+ *   A republisher, which does nothing until Start is called
+ */
+func NewRepublisher(server *Server, interval time.Duration) *Republisher {
+	if interval <= 0 {
+		interval = DefaultRepublishInterval
+	}
+	return &Republisher{
+		server:   server,
+		interval: interval,
+		listings: make(map[string]*listing),
+		records:  make(map[string]*signedRecord),
+		putting:  make(map[string]chan struct{}),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+/*
+ * Remember a file so its record is republished. Tracking a file again replaces the
+ * details of the previous listing.
+ *
+ * Parameters:
+ *   hash: The hash of the registered file
+ *   user: The producer details that were registered
+ *   ttl: The TTL of the registered record in seconds
+ *   keywords: The normalized keywords the file is indexed under
+ */
+func (r *Republisher) Track(hash string, user *User, ttl uint32, keywords []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listings[hash] = &listing{
+		user:     proto.Clone(user).(*User),
+		ttl:      ttl,
+		keywords: keywords,
+		next:     time.Now().Add(r.jitter(r.period(ttl))),
+	}
+	r.signal()
+}
+
+/*
+ * Keep a signed entry of a reputation or metadata chain on the DHT until it expires.
+ * Tracking an entry under the same key again replaces it.
+ *
+ * Parameters:
+ *   key: The DHT key of the chain
+ *   entry: The entry, as it was signed
+ */
+func (r *Republisher) TrackRecord(key string, entry record.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[key] = &signedRecord{entry: entry, next: time.Now().Add(r.jitter(r.interval))}
+	r.signal()
+}
+
+// Wake the loop to work out its next wait again, without blocking if it is already due to.
+func (r *Republisher) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+/*
+ * Stop republishing the record for a file. If the file is being republished right now,
+ * wait for that put to finish, so a record written after Untrack returns is newer than
+ * anything the republisher put for it.
+ *
+ * Parameters:
+ *   hash: The hash of the file to forget
+ *
+ * Returns:
+ *   The file's user, TTL and keywords, to Track it again with, or nil if it was not tracked
+ */
+func (r *Republisher) Untrack(hash string) *Registration {
+	r.mu.Lock()
+	l, ok := r.listings[hash]
+	delete(r.listings, hash)
+	putting := r.putting[hash]
+	r.mu.Unlock()
+
+	if putting != nil {
+		<-putting
+	}
+	if !ok {
+		return nil
+	}
+	return &Registration{FileHash: hash, User: l.user, Ttl: l.ttl, Keywords: l.keywords}
+}
+
+/*
+ * Start republishing in the background until Stop is called or ctx is done.
+ *
+ * Parameters:
+ *   ctx: Context
+ */
+func (r *Republisher) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	go r.run(ctx)
+}
+
+/*
+ * Stop the background republisher, aborting any republish in progress, and wait for
+ * it to exit.
+ */
+func (r *Republisher) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+func (r *Republisher) run(ctx context.Context) {
+	defer close(r.done)
+	timer := time.NewTimer(r.nextWait())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+			//something was tracked, and may be due before the timer fires
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(r.nextWait())
+			continue
+		case <-timer.C:
+		}
+		r.republishDue(ctx)
+		timer.Reset(r.nextWait())
+	}
+}
+
+// Republish every listing whose time has come, one at a time.
+func (r *Republisher) republishDue(ctx context.Context) {
+	now := time.Now()
+	r.mu.Lock()
+	due := make(map[string]*listing)
+	for hash, l := range r.listings {
+		if !l.next.After(now) {
+			due[hash] = l
+		}
+	}
+	r.mu.Unlock()
+
+	for hash, l := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		r.mu.Lock()
+		// the listing may have been replaced or removed since the snapshot, and once it is
+		// marked as being put, Untrack waits for us before the file can be withdrawn
+		if r.listings[hash] != l {
+			r.mu.Unlock()
+			continue
+		}
+		putting := make(chan struct{})
+		r.putting[hash] = putting
+		r.mu.Unlock()
+
+		putCtx, cancel := context.WithTimeout(ctx, republishTimeout)
+		_, err := r.server.putListing(putCtx, hash, proto.Clone(l.user).(*User), l.ttl)
+		if err == nil {
+			//index entries expire too, so they are renewed along with the listing, but the
+			//listing is republished whether or not they could be
+			if indexErr := r.server.indexFile(putCtx, hash, l.keywords, true); indexErr != nil {
+				log.Printf("Failed to index %s under its keywords: %v", hash, indexErr)
+			}
+			r.server.markPut(putCtx, hash)
+		}
+		cancel()
+
+		r.mu.Lock()
+		delete(r.putting, hash)
+		close(putting)
+		// the listing may have been replaced or removed while we were putting it
+		if r.listings[hash] == l {
+			if err != nil {
+				l.failures++
+				l.next = time.Now().Add(r.jitter(r.backoff(l.failures, r.period(l.ttl))))
+				log.Printf("Failed to republish %s (attempt %d): %v", hash, l.failures, err)
+			} else {
+				l.failures = 0
+				l.next = time.Now().Add(r.jitter(r.period(l.ttl)))
+			}
+		}
+		r.mu.Unlock()
+	}
+	r.republishRecordsDue(ctx)
+}
+
+// Put every signed entry whose time has come again, one at a time, and forget those that
+// have expired or been replaced by a newer entry of ours.
+func (r *Republisher) republishRecordsDue(ctx context.Context) {
+	now := time.Now()
+	r.mu.Lock()
+	due := make(map[string]*signedRecord)
+	for key, sr := range r.records {
+		if !sr.next.After(now) {
+			due[key] = sr
+		}
+	}
+	r.mu.Unlock()
+
+	for key, sr := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		done := sr.entry.Expired(uint64(time.Now().UTC().Unix()))
+		var err error
+		if !done {
+			putCtx, cancel := context.WithTimeout(ctx, republishTimeout)
+			done, err = r.server.putSignedRecord(putCtx, key, sr.entry)
+			cancel()
+		}
+
+		r.mu.Lock()
+		forget := false
+		if r.records[key] == sr {
+			if done {
+				delete(r.records, key)
+				forget = true
+			} else if err != nil {
+				sr.failures++
+				sr.next = time.Now().Add(r.jitter(r.backoff(sr.failures, r.interval)))
+				log.Printf("Failed to republish %s (attempt %d): %v", key, sr.failures, err)
+			} else {
+				sr.failures = 0
+				sr.next = time.Now().Add(r.jitter(r.interval))
+			}
+		}
+		r.mu.Unlock()
+		if forget {
+			r.server.forgetRecord(ctx, key, sr.entry)
+		}
+	}
+}
+
+// Time until the next listing or signed entry is due, or a full interval if nothing is
+// tracked.
+func (r *Republisher) nextWait() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wait := r.interval
+	for _, l := range r.listings {
+		if until := time.Until(l.next); until < wait {
+			wait = until
+		}
+	}
+	for _, sr := range r.records {
+		if until := time.Until(sr.next); until < wait {
+			wait = until
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// Republish period for a record, leaving time to retry before it expires.
+func (r *Republisher) period(ttl uint32) time.Duration {
+	half := time.Duration(ttl) * time.Second / 2
+	if half < r.interval {
+		return half
+	}
+	return r.interval
+}
+
+// Exponential backoff after a number of failures, never longer than the normal period.
+func (r *Republisher) backoff(failures int, period time.Duration) time.Duration {
+	delay := republishRetryDelay
+	for i := 1; i < failures && delay < period; i++ {
+		delay *= 2
+	}
+	if delay > period {
+		return period
+	}
+	return delay
+}
+
+// Shorten d by up to 10% so listings registered together do not republish together.
+func (r *Republisher) jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d - time.Duration(rand.Int63n(int64(d)/10+1))
+}
+
+/*
+ * Find how the entries of a chain whose signed entries the republisher keeps are told
+ * apart and capped.
+ *
+ * Parameters:
+ *   key: The DHT key of the chain
+ *
+ * Returns:
+ *   Finds the canonical id of the public key an entry belongs to
+ *   Most entries the chain may hold
+ *   An error, if the key is not of such a chain
+ */
+func signedChain(key string) (func(*record.Entry) ([]byte, error), int, error) {
+	switch {
+	case strings.HasPrefix(key, reputationKey("")):
+		return ratingRaterID, record.MaxRaters, nil
+	case strings.HasPrefix(key, metadataKey("")):
+		return metadataPublisherID, record.MaxMetadataPublishers, nil
+	}
+	return nil, 0, fmt.Errorf("no signed records are kept under %s", key)
+}
+
+/*
+ * Merge a signed entry of ours into the reputation or metadata chain under key, as it was
+ * signed.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   key: The DHT key of the chain
+ *   entry: The signed entry
+ *
+ * Returns:
+ *   True, without putting anything, if the chain already holds a newer entry of ours
+ *   An error, if any
+ */
+func (s *Server) putSignedRecord(ctx context.Context, key string, entry record.Entry) (bool, error) {
+	entryID, max, err := signedChain(key)
+	if err != nil {
+		return false, err
+	}
+	id, err := entryID(&entry)
+	if err != nil {
+		return false, err
+	}
+	return s.mergeChainEntry(ctx, key, entry, id, entryID, max)
+}
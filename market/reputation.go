@@ -0,0 +1,164 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// Lifetime of a rating, the longest the validator allows. Ratings are not signed again, but
+// the rater's node puts its rating back on the DHT until it lapses, see TrackRecord. A
+// rating can still be pushed out of a full chain sooner by newer ratings, see
+// record.MaxRaters.
+const DefaultRatingTTL = record.MaxRatingTTL
+
+/*
+ * gRPC service to rate a producer. The rating is signed with the server's key and merged
+ * into the producer's reputation chain under orcanet/reputation/<peer>, replacing any
+ * earlier rating of the producer by this node. It is republished until it expires.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf RateProducerRequest struct holding the producer's id and the score.
+ *
+ * Returns:
+ *   An empty protobuf struct
+ *   An InvalidArgument status if the producer id or score is invalid, the comment is longer
+ *   than record.MaxCommentLength or the producer is this node, or any other error
+ */
+func (s *Server) RateProducer(ctx context.Context, in *RateProducerRequest) (*emptypb.Empty, error) {
+	producer, err := producerPeer(in.GetProducer())
+	if err != nil {
+		return nil, err
+	}
+	if in.GetScore() < record.MinRatingScore || in.GetScore() > record.MaxRatingScore {
+		return nil, status.Errorf(codes.InvalidArgument, "score must be between %d and %d", record.MinRatingScore, record.MaxRatingScore)
+	}
+	if len(in.GetComment()) > record.MaxCommentLength {
+		return nil, status.Errorf(codes.InvalidArgument, "comment must be at most %d bytes", record.MaxCommentLength)
+	}
+	self, err := peer.IDFromPublicKey(s.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	if producer == self {
+		return nil, status.Error(codes.InvalidArgument, "a node cannot rate itself")
+	}
+
+	raterID, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		return nil, err
+	}
+	message, err := proto.Marshal(&Rating{Rater: raterID, Score: in.GetScore(), Comment: in.GetComment()})
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.signEntry(record.ReputationSignatureDomain, producer.String(), message, DefaultRatingTTL)
+	if err != nil {
+		return nil, err
+	}
+	key := reputationKey(producer)
+	if _, err := s.mergeChainEntry(ctx, key, entry, raterID, ratingRaterID, record.MaxRaters); err != nil {
+		return nil, err
+	}
+	s.keepRecord(ctx, key, entry)
+	return &emptypb.Empty{}, nil
+}
+
+/*
+ * gRPC service to get the reputation of a producer from its reputation chain.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf GetReputationRequest struct holding the producer's id.
+ *
+ * Returns:
+ *   The producer's mean score and unexpired ratings, empty if it has not been rated
+ *   An InvalidArgument status if the producer id is invalid, or any other error
+ */
+func (s *Server) GetReputation(ctx context.Context, in *GetReputationRequest) (*Reputation, error) {
+	producer, err := producerPeer(in.GetProducer())
+	if err != nil {
+		return nil, err
+	}
+	return s.reputation(ctx, producer)
+}
+
+/*
+ * Look up and aggregate the ratings of a producer.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   producer: The peer ID of the producer
+ *
+ * Returns:
+ *   The producer's mean score and unexpired ratings, empty if it has not been rated
+ *   An error, if the lookup failed
+ */
+func (s *Server) reputation(ctx context.Context, producer peer.ID) (*Reputation, error) {
+	reputation := &Reputation{Ratings: make([]*Rating, 0)}
+	chain, err := s.getMergedChain(ctx, reputationKey(producer))
+	if errors.Is(err, routing.ErrNotFound) {
+		return reputation, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	total := uint64(0)
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		if entry.Expired(now) {
+			continue
+		}
+		rating := &Rating{}
+		if err := proto.Unmarshal(entry.Message, rating); err != nil {
+			return nil, err
+		}
+		reputation.Ratings = append(reputation.Ratings, rating)
+		total += uint64(rating.GetScore())
+	}
+
+	reputation.RatingCount = uint32(len(reputation.Ratings))
+	if reputation.RatingCount > 0 {
+		reputation.Score = float64(total) / float64(reputation.RatingCount)
+	}
+	return reputation, nil
+}
+
+// The peer ID of a producer given its User.id, or an InvalidArgument status.
+func producerPeer(producer []byte) (peer.ID, error) {
+	pubKey, err := util.UnmarshalUserID(producer)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid producer id: %v", err)
+	}
+	id, err := peer.IDFromPublicKey(pubKey)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid producer id: %v", err)
+	}
+	return id, nil
+}
+
+// The DHT key of a producer's reputation chain.
+func reputationKey(producer peer.ID) string {
+	return "orcanet/reputation/" + producer.String()
+}
+
+// The canonical id of the public key of the rater in a reputation chain entry.
+func ratingRaterID(entry *record.Entry) ([]byte, error) {
+	rating := &Rating{}
+	if err := proto.Unmarshal(entry.Message, rating); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(rating.GetRater())
+}
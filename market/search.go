@@ -0,0 +1,347 @@
+package market
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/routing"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+const (
+	// Most keywords a file may be registered under, or a search may ask for.
+	MaxKeywords = 16
+	// Most files matching a search whose holders are looked up.
+	MaxSearchCandidates = 128
+)
+
+/*
+ * gRPC service to find files by keyword. Every keyword's index chain under
+ * orcanet/index/<keyword> is looked up, and the files listed under all of the keywords
+ * are ranked by how many live holders they have. Files without holders are left out.
+ * Only the MaxSearchCandidates files listed by the most nodes are looked up, so a search
+ * for common keywords costs a bounded number of lookups.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   in: A protobuf SearchRequest struct holding the keywords.
+ *
+ * Returns:
+ *   A SearchResponse holding the matching files, most holders first
+ *   An InvalidArgument status if there are no keywords, more than MaxKeywords or an
+ *   invalid one, or any other error
+ */
+func (s *Server) Search(ctx context.Context, in *SearchRequest) (*SearchResponse, error) {
+	//checked before normalizing, so a long list of duplicates is not worked through either
+	if len(in.GetKeywords()) > MaxKeywords {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d keywords are allowed", MaxKeywords)
+	}
+	keywords, err := normalizeKeywords(in.GetKeywords())
+	if err != nil {
+		return nil, err
+	}
+	if len(keywords) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one keyword is required")
+	}
+
+	sets := make([]map[string]int, len(keywords))
+	errs := make([]error, len(keywords))
+	s.forEachConcurrently(len(keywords), func(i int) {
+		sets[i], errs[i] = s.indexedFiles(ctx, keywords[i])
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	hashes := searchCandidates(sets)
+	counts := make([]int, len(hashes))
+	s.forEachConcurrently(len(hashes), func(i int) {
+		holders, err := s.fileHolders(ctx, hashes[i], uint64(time.Now().UTC().Unix()))
+		if err != nil {
+			log.Printf("Failed to look up holders of %s for search: %v", hashes[i], err)
+			return
+		}
+		counts[i] = len(holders)
+	})
+
+	results := make([]*SearchResult, 0, len(hashes))
+	for i, hash := range hashes {
+		if counts[i] > 0 {
+			results = append(results, &SearchResult{FileHash: hash, HolderCount: uint32(counts[i])})
+		}
+	}
+	slices.SortFunc(results, func(a, b *SearchResult) int {
+		if c := cmp.Compare(b.GetHolderCount(), a.GetHolderCount()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.GetFileHash(), b.GetFileHash())
+	})
+	if limit := int(in.GetLimit()); limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return &SearchResponse{Results: results}, nil
+}
+
+/*
+ * Find the files listed under every keyword of a search, keeping the MaxSearchCandidates
+ * listed by the most nodes under the keyword they are listed least under. A file listed
+ * by more nodes likely has more holders, ties are broken on the hash.
+ *
+ * Parameters:
+ *   sets: How many nodes list each file hash, for every keyword
+ *
+ * Returns:
+ *   The file hashes, most listed first
+ */
+func searchCandidates(sets []map[string]int) []string {
+	hashes := make([]string, 0)
+	listings := make(map[string]int)
+	for hash, count := range sets[0] {
+		for _, set := range sets[1:] {
+			count = min(count, set[hash])
+		}
+		if count > 0 {
+			hashes = append(hashes, hash)
+			listings[hash] = count
+		}
+	}
+	slices.SortFunc(hashes, func(a, b string) int {
+		if c := cmp.Compare(listings[b], listings[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	if len(hashes) > MaxSearchCandidates {
+		hashes = hashes[:MaxSearchCandidates]
+	}
+	return hashes
+}
+
+/*
+ * Look up every file listed under a keyword by any node.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   keyword: The normalized keyword
+ *
+ * Returns:
+ *   How many nodes list each file hash, empty if the keyword is not indexed
+ *   An error, if the lookup failed
+ */
+func (s *Server) indexedFiles(ctx context.Context, keyword string) (map[string]int, error) {
+	files := make(map[string]int)
+	chain, err := s.getMergedChain(ctx, indexKey(keyword))
+	if errors.Is(err, routing.ErrNotFound) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	for i := range chain.Entries {
+		if chain.Entries[i].Expired(now) {
+			continue
+		}
+		index := &IndexEntry{}
+		if err := proto.Unmarshal(chain.Entries[i].Message, index); err != nil {
+			return nil, err
+		}
+		for _, hash := range index.GetFileHashes() {
+			files[hash]++
+		}
+	}
+	return files, nil
+}
+
+/*
+ * Add a file to, or remove it from, this node's entry in the index chain of every keyword.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file
+ *   keywords: The normalized keywords
+ *   add: True to add the file, false to remove it
+ *
+ * Returns:
+ *   An error for each keyword that could not be updated, joined
+ */
+func (s *Server) indexFile(ctx context.Context, hash string, keywords []string, add bool) error {
+	errs := make([]error, 0)
+	for _, keyword := range keywords {
+		if err := s.updateIndex(ctx, keyword, hash, add); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+ * Add a file to, or remove it from, this node's entry in a keyword's index chain. The
+ * entry is signed again either way, which keeps it from expiring while the node still
+ * holds files under the keyword.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   keyword: The normalized keyword
+ *   hash: The hash of the file
+ *   add: True to add the file, false to remove it
+ *
+ * Returns:
+ *   A ResourceExhausted status if this node already lists record.MaxIndexedFiles files
+ *   under the keyword, or any other error
+ */
+func (s *Server) updateIndex(ctx context.Context, keyword string, hash string, add bool) error {
+	key := indexKey(keyword)
+	publisherID, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		return err
+	}
+
+	chain, err := s.getMergedChain(ctx, key)
+	if errors.Is(err, routing.ErrNotFound) {
+		chain = &record.Chain{}
+	} else if err != nil {
+		return err
+	}
+	previous, err := removeEntry(chain, publisherID, uint64(time.Now().UTC().Unix()), indexPublisherID)
+	if err != nil {
+		return err
+	}
+
+	index := &IndexEntry{}
+	if previous != nil {
+		if err := proto.Unmarshal(previous.Message, index); err != nil {
+			return err
+		}
+	}
+	index.Publisher = publisherID
+	i, found := slices.BinarySearch(index.FileHashes, hash)
+	switch {
+	case add && !found:
+		if len(index.FileHashes) >= record.MaxIndexedFiles {
+			return indexFullError(keyword)
+		}
+		index.FileHashes = slices.Insert(index.FileHashes, i, hash)
+	case !add && !found:
+		return nil
+	case !add && found:
+		index.FileHashes = slices.Delete(index.FileHashes, i, i+1)
+	}
+
+	message, err := proto.Marshal(index)
+	if err != nil {
+		return err
+	}
+	entry, err := s.signEntry(record.IndexSignatureDomain, keyword, message, record.MaxEntryTTL)
+	if err != nil {
+		return err
+	}
+	//our entry is the newest, so a full chain drops the entry renewed longest ago instead
+	chain.Entries = append(chain.Entries, entry)
+	chain.KeepNewest(record.MaxIndexPublishers)
+
+	value, err := record.Encode(chain)
+	if err != nil {
+		return err
+	}
+	return s.K_DHT.PutValue(ctx, key, value)
+}
+
+/*
+ * Check that a file can be added to this node's entry in the index chain of every keyword,
+ * before anything is put for it. A keyword whose chain cannot be looked up is not
+ * checked, adding the file to it fails later instead.
+ *
+ * Parameters:
+ *   ctx: Context
+ *   hash: The hash of the file
+ *   keywords: The normalized keywords
+ *
+ * Returns:
+ *   A ResourceExhausted status if this node already lists record.MaxIndexedFiles other
+ *   files under a keyword
+ */
+func (s *Server) checkIndexLimits(ctx context.Context, hash string, keywords []string) error {
+	publisherID, err := util.MarshalUserID(s.PubKey)
+	if err != nil {
+		return err
+	}
+	now := uint64(time.Now().UTC().Unix())
+	errs := make([]error, len(keywords))
+	s.forEachConcurrently(len(keywords), func(i int) {
+		chain, err := s.getMergedChain(ctx, indexKey(keywords[i]))
+		if err != nil {
+			return
+		}
+		previous, err := removeEntry(chain, publisherID, now, indexPublisherID)
+		if err != nil || previous == nil {
+			return
+		}
+		index := &IndexEntry{}
+		if err := proto.Unmarshal(previous.Message, index); err != nil {
+			return
+		}
+		_, found := slices.BinarySearch(index.GetFileHashes(), hash)
+		if !found && len(index.GetFileHashes()) >= record.MaxIndexedFiles {
+			errs[i] = indexFullError(keywords[i])
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The status returned when this node lists as many files under a keyword as it may.
+func indexFullError(keyword string) error {
+	return status.Errorf(codes.ResourceExhausted, "this node already lists %d files under keyword %q", record.MaxIndexedFiles, keyword)
+}
+
+/*
+ * Normalize keywords with util.NormalizeKeyword, dropping duplicates.
+ *
+ * Returns:
+ *   The normalized keywords, in the order first given
+ *   An InvalidArgument status if a keyword is invalid or there are more than MaxKeywords
+ */
+func normalizeKeywords(keywords []string) ([]string, error) {
+	normalized := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		k, err := util.NormalizeKeyword(keyword)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if !slices.Contains(normalized, k) {
+			normalized = append(normalized, k)
+		}
+	}
+	if len(normalized) > MaxKeywords {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d keywords are allowed", MaxKeywords)
+	}
+	return normalized, nil
+}
+
+// The DHT key of a keyword's index chain.
+func indexKey(keyword string) string {
+	return "orcanet/index/" + keyword
+}
+
+// The canonical id of the public key of the publisher in an index chain entry.
+func indexPublisherID(entry *record.Entry) ([]byte, error) {
+	index := &IndexEntry{}
+	if err := proto.Unmarshal(entry.Message, index); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(index.GetPublisher())
+}
@@ -0,0 +1,62 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Keyword lists over MaxKeywords are rejected before any keyword is looked up, even when
+// they only repeat one keyword.
+func TestSearchKeywordLimit(t *testing.T) {
+	s := &Server{}
+	repeated := make([]string, MaxKeywords+1)
+	for i := range repeated {
+		repeated[i] = "music"
+	}
+	tests := [][]string{make([]string, MaxKeywords+1), repeated, {}}
+	for _, keywords := range tests {
+		_, err := s.Search(context.Background(), &SearchRequest{Keywords: keywords})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Search with %d keywords returned %v, want InvalidArgument", len(keywords), err)
+		}
+	}
+}
+
+// Only files listed under every keyword are candidates, and only the MaxSearchCandidates
+// listed by the most nodes are looked up.
+func TestSearchCandidates(t *testing.T) {
+	tests := []struct {
+		name string
+		sets []map[string]int
+		want []string
+	}{
+		{"one keyword", []map[string]int{{"a": 1, "b": 3, "c": 2}}, []string{"b", "c", "a"}},
+		{"ties by hash", []map[string]int{{"c": 1, "a": 1, "b": 1}}, []string{"a", "b", "c"}},
+		{"intersection", []map[string]int{{"a": 1, "b": 1, "c": 1}, {"b": 1, "c": 1, "d": 1}}, []string{"b", "c"}},
+		{"ranked by the least listed keyword", []map[string]int{{"a": 5, "b": 2}, {"a": 1, "b": 2}}, []string{"b", "a"}},
+		{"nothing in common", []map[string]int{{"a": 1}, {"b": 1}}, []string{}},
+		{"nothing listed", []map[string]int{{}}, []string{}},
+	}
+	for _, test := range tests {
+		if got := searchCandidates(test.sets); !slices.Equal(got, test.want) {
+			t.Errorf("%s: searchCandidates = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	listed := make(map[string]int)
+	for i := 0; i < 2*MaxSearchCandidates; i++ {
+		listed[fmt.Sprintf("%04d", i)] = i
+	}
+	got := searchCandidates([]map[string]int{listed})
+	if len(got) != MaxSearchCandidates {
+		t.Fatalf("searchCandidates kept %d of %d files, want %d", len(got), len(listed), MaxSearchCandidates)
+	}
+	if got[0] != fmt.Sprintf("%04d", 2*MaxSearchCandidates-1) || got[len(got)-1] != fmt.Sprintf("%04d", MaxSearchCandidates) {
+		t.Errorf("searchCandidates kept %s to %s, want the most listed files", got[0], got[len(got)-1])
+	}
+}
@@ -0,0 +1,108 @@
+package market
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// How often WatchHolders looks up the chain for a file when the server does not set one.
+const DefaultWatchInterval = 30 * time.Second
+
+/*
+ * gRPC service to stream changes to the holders of a file. Every current holder is sent
+ * as added when the watch starts, after which the chain is looked up again every
+ * WatchInterval and the differences are sent. Holders are told apart by public key, so a
+ * holder that re-registers with new details is sent as updated. The price and age filters
+ * of the request apply, a holder that stops passing them is sent as removed. The sort
+ * order, limit and page token are ignored.
+ *
+ * Parameters:
+ *   in: A protobuf CheckHoldersRequest struct that represents the file to watch.
+ *   stream: The stream to send HolderEvents on
+ *
+ * Returns:
+ *   Nil once the client cancels, an InvalidArgument status if the file hash is malformed,
+ *   or an error if an event could not be sent
+ */
+func (s *Server) WatchHolders(in *CheckHoldersRequest, stream Market_WatchHoldersServer) error {
+	ctx := stream.Context()
+	hash, err := normalizeFileHash(in.GetFileHash())
+	if err != nil {
+		return err
+	}
+	interval := s.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	known := make(map[string]*User)
+	for {
+		current, err := s.holdersByID(ctx, hash, in)
+		if err != nil && ctx.Err() == nil {
+			//keep what we know and try again, a failed lookup does not mean holders left
+			log.Printf("Failed to look up holders of %s for watch: %v", hash, err)
+		} else if err == nil {
+			if err := sendHolderChanges(stream, known, current); err != nil {
+				return err
+			}
+			known = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+ * Look up the live holders of a file that pass the request's filters, keyed by the
+ * canonical id of their public key.
+ *
+ * Returns:
+ *   The holders of the file, empty if nobody has registered it
+ *   An error, if the lookup failed
+ */
+func (s *Server) holdersByID(ctx context.Context, hash string, in *CheckHoldersRequest) (map[string]*User, error) {
+	holders := make(map[string]*User)
+	now := uint64(time.Now().UTC().Unix())
+	live, err := s.fileHolders(ctx, hash, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range filterHolders(live, in, now) {
+		holders[string(h.id)] = h.user
+	}
+	return holders, nil
+}
+
+// Send an event for every holder that was added, removed or updated between two lookups.
+func sendHolderChanges(stream Market_WatchHoldersServer, previous map[string]*User, current map[string]*User) error {
+	for id, user := range current {
+		old, ok := previous[id]
+		if !ok {
+			if err := stream.Send(&HolderEvent{Type: HolderEvent_ADDED, Holder: user}); err != nil {
+				return err
+			}
+		} else if !proto.Equal(old, user) {
+			if err := stream.Send(&HolderEvent{Type: HolderEvent_UPDATED, Holder: user}); err != nil {
+				return err
+			}
+		}
+	}
+	for id, user := range previous {
+		if _, ok := current[id]; !ok {
+			if err := stream.Send(&HolderEvent{Type: HolderEvent_REMOVED, Holder: user}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
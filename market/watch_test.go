@@ -0,0 +1,67 @@
+package market
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+// Records the events sent on a watch, failing every send once err is set.
+type fakeWatchStream struct {
+	grpc.ServerStream
+	events []string
+	err    error
+}
+
+func (s *fakeWatchStream) Send(event *HolderEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, event.GetType().String()+" "+event.GetHolder().GetName())
+	return nil
+}
+
+// Holders are told apart by id, and each one that differs between two lookups is sent once.
+func TestSendHolderChanges(t *testing.T) {
+	alice := &User{Name: "alice", Price: 10}
+	bob := &User{Name: "bob", Price: 20}
+	carol := &User{Name: "carol", Price: 30}
+	tests := []struct {
+		name     string
+		previous map[string]*User
+		current  map[string]*User
+		want     []string
+	}{
+		{"nothing known", map[string]*User{}, map[string]*User{}, nil},
+		{"first lookup", map[string]*User{}, map[string]*User{"a": alice, "b": bob}, []string{"ADDED alice", "ADDED bob"}},
+		{"unchanged", map[string]*User{"a": alice, "b": bob}, map[string]*User{"a": {Name: "alice", Price: 10}, "b": bob}, nil},
+		{"removed", map[string]*User{"a": alice, "b": bob}, map[string]*User{"b": bob}, []string{"REMOVED alice"}},
+		{"updated", map[string]*User{"a": alice}, map[string]*User{"a": {Name: "alice", Price: 15}}, []string{"UPDATED alice"}},
+		{"all at once", map[string]*User{"a": alice, "b": bob}, map[string]*User{"a": {Name: "alice", Price: 15}, "c": carol}, []string{"ADDED carol", "REMOVED bob", "UPDATED alice"}},
+		{"same details, other key", map[string]*User{"a": alice}, map[string]*User{"b": {Name: "alice", Price: 10}}, []string{"ADDED alice", "REMOVED alice"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := &fakeWatchStream{}
+			if err := sendHolderChanges(stream, test.previous, test.current); err != nil {
+				t.Fatal(err)
+			}
+			//holders come out of maps, so the order of events is not defined
+			slices.Sort(stream.events)
+			if !slices.Equal(stream.events, test.want) {
+				t.Errorf("sent %v, want %v", stream.events, test.want)
+			}
+		})
+	}
+}
+
+func TestSendHolderChangesSendError(t *testing.T) {
+	failed := errors.New("stream closed")
+	stream := &fakeWatchStream{err: failed}
+	err := sendHolderChanges(stream, map[string]*User{}, map[string]*User{"a": {Name: "alice"}})
+	if !errors.Is(err, failed) {
+		t.Errorf("sendHolderChanges returned %v, want the send error", err)
+	}
+}
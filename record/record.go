@@ -0,0 +1,329 @@
+/*
+ * Codec for the holder chains stored under orcanet/market/<hash> on the DHT, which is also
+ * used for the reputation chains under orcanet/reputation/<peer>, the metadata chains
+ * under orcanet/meta/<hash>, the keyword index chains under orcanet/index/<keyword> and the
+ * holder profiles under orcanet/profile/<peer>/<hash>. The market server and
+ * the validator both read and write chains through this package, see /validator/README.md
+ * for the byte layout.
+ */
+package record
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// Version of the chain format written by Encode.
+	Version = 1
+	// Length of the magic number and version at the start of every chain.
+	HeaderLength = len(Magic) + 1
+	// Size of the registration time, TTL and flags that precede each signed message.
+	EntryMetadataLength = 13
+	// Longest user message an entry may hold.
+	MaxMessageLength = 4096
+	// Longest signature an entry may hold, enough for a 8192 bit RSA key.
+	MaxSignatureLength = 1024
+	// Longest lifetime an entry may claim.
+	MaxEntryTTL = 7 * 24 * time.Hour
+	// Longest lifetime an entry of a reputation chain may claim. Ratings outlive listings,
+	// so a producer's reputation does not reset every week.
+	MaxRatingTTL = 90 * 24 * time.Hour
+	// Longest lifetime an entry of a metadata chain may claim. What a file is does not
+	// change, so metadata lasts as long as ratings.
+	MaxMetadataTTL = MaxRatingTTL
+	// Most files a node may list under one keyword, so its index entry stays within
+	// MaxMessageLength whatever its key type.
+	MaxIndexedFiles = 50
+	// Most entries a chain with many signers may hold, so a common keyword, a popular
+	// producer or a popular file does not grow one value past what a DHT message can carry.
+	// Merging keeps the newest entries of a chain over its cap, see KeepNewest.
+	MaxIndexPublishers    = 32
+	MaxRaters             = 64
+	MaxMetadataPublishers = 32
+	// Range of the score of a rating.
+	MinRatingScore = 1
+	MaxRatingScore = 5
+	// Longest comment a rating may hold in bytes, so its message stays within
+	// MaxMessageLength whatever its key type.
+	MaxCommentLength = 2048
+	// Flag marking an entry as withdrawn by its owner. It shadows older entries for the
+	// same key when chains are merged, and is not reported as a holder.
+	FlagWithdrawn = 1 << 0
+	// Domain separation tag at the start of every signed payload, so an entry signature
+	// cannot be mistaken for a signature over anything else.
+	SignatureDomain = "orcanet/market/record/v1"
+	// Domain separation tag for entries of reputation chains.
+	ReputationSignatureDomain = "orcanet/reputation/record/v1"
+	// Domain separation tag for entries of metadata chains.
+	MetadataSignatureDomain = "orcanet/meta/record/v1"
+	// Domain separation tag for entries of keyword index chains.
+	IndexSignatureDomain = "orcanet/index/record/v1"
+	// Domain separation tag for the entry of a holder's profile.
+	ProfileSignatureDomain = "orcanet/profile/record/v1"
+	// Number of shards the holder chain of a file is split across once it is full, stored
+	// under orcanet/market/<hash>/<n> for n below ShardCount.
+	ShardCount = 16
+	// Live entries the holder chain under orcanet/market/<hash> may hold before it is full.
+	// While it is full new entries go to the shards, and it is stored with exactly this
+	// many entries so readers know to look them up. A chain holding more is invalid.
+	ShardThreshold = 64
+	// Most entries a shard of a holder chain may hold. Merging keeps the newest entries of
+	// a shard over it, see KeepNewest, so a shard crowded by many keys still takes new ones.
+	MaxShardEntries = 64
+)
+
+// Identifies a value as an OrcaNet holder chain.
+const Magic = "ORCA"
+
+var (
+	// The value does not start with Magic.
+	ErrNotChain = errors.New("record: value is not a chain")
+	// The chain was written in a format version we do not know.
+	ErrUnsupportedVersion = errors.New("record: unsupported chain version")
+	// The value ends in the middle of an entry.
+	ErrTruncated = errors.New("record: chain is truncated")
+	// An entry declares a message or signature longer than the format allows.
+	ErrLengthOverflow = errors.New("record: entry length exceeds limit")
+	// The value has bytes after the last entry that are too short to be an entry.
+	ErrTrailingData = errors.New("record: trailing data after last entry")
+)
+
+// An error found while decoding a chain, along with where in the value it was found.
+// Use errors.Is to compare it against the Err* values of this package.
+type DecodeError struct {
+	Offset int
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("%v at offset %d", e.Err, e.Offset)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// A single signed entry of a chain. Holder chains hold User messages, reputation chains
+// hold Rating messages, metadata chains hold FileMetadata messages and index chains hold
+// IndexEntry messages.
+type Entry struct {
+	// Unix time the entry was signed at.
+	RegisteredAt uint64
+	// Seconds the entry stays valid for after RegisteredAt.
+	TTL   uint32
+	Flags byte
+	// The marshalled protocol buffer message.
+	Message   []byte
+	Signature []byte
+}
+
+// A list of signed entries, at most one per public key.
+type Chain struct {
+	Entries []Entry
+}
+
+/*
+ * Encode a chain into the byte format stored on the DHT.
+ *
+ * Parameters:
+ *   chain: The chain to encode
+ *
+ * Returns:
+ *   The encoded chain
+ *   ErrLengthOverflow if a message or signature is too long to encode
+ */
+func Encode(chain *Chain) ([]byte, error) {
+	value := make([]byte, 0, HeaderLength)
+	value = append(value, Magic...)
+	value = append(value, Version)
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		if len(entry.Message) > MaxMessageLength || len(entry.Signature) > MaxSignatureLength {
+			return nil, fmt.Errorf("entry %d: %w", i, ErrLengthOverflow)
+		}
+		value = binary.LittleEndian.AppendUint16(value, uint16(len(entry.Message)))
+		value = binary.LittleEndian.AppendUint16(value, uint16(len(entry.Signature)))
+		value = append(value, entry.SignedBytes()...)
+		value = append(value, entry.Signature...)
+	}
+	return value, nil
+}
+
+/*
+ * Decode a chain from the byte format stored on the DHT. Values come from remote peers,
+ * so every length is checked before it is used and malformed input is reported as an
+ * error rather than a panic. The entries of the returned chain reference value, so value
+ * must not be modified while the chain is in use.
+ *
+ * Parameters:
+ *   value: The encoded chain
+ *
+ * Returns:
+ *   The decoded chain
+ *   A *DecodeError wrapping ErrNotChain, ErrUnsupportedVersion, ErrTruncated,
+ *   ErrLengthOverflow or ErrTrailingData if the value is not a well formed chain
+ */
+func Decode(value []byte) (*Chain, error) {
+	if len(value) < len(Magic) || string(value[:len(Magic)]) != Magic {
+		return nil, &DecodeError{Offset: 0, Err: ErrNotChain}
+	}
+	if len(value) < HeaderLength {
+		return nil, &DecodeError{Offset: len(Magic), Err: ErrTruncated}
+	}
+	if value[len(Magic)] != Version {
+		return nil, &DecodeError{Offset: len(Magic), Err: ErrUnsupportedVersion}
+	}
+
+	chain := &Chain{Entries: make([]Entry, 0)}
+	for i := HeaderLength; i < len(value); {
+		remaining := len(value) - i
+		if remaining < 4 {
+			return nil, &DecodeError{Offset: i, Err: ErrTrailingData}
+		}
+		messageLength := int(binary.LittleEndian.Uint16(value[i:]))
+		signatureLength := int(binary.LittleEndian.Uint16(value[i+2:]))
+		if messageLength > MaxMessageLength || signatureLength > MaxSignatureLength {
+			return nil, &DecodeError{Offset: i, Err: ErrLengthOverflow}
+		}
+		if remaining < 4+EntryMetadataLength+messageLength+signatureLength {
+			return nil, &DecodeError{Offset: i, Err: ErrTruncated}
+		}
+
+		metadata := value[i+4 : i+4+EntryMetadataLength]
+		messageStart := i + 4 + EntryMetadataLength
+		end := messageStart + messageLength + signatureLength
+		chain.Entries = append(chain.Entries, Entry{
+			RegisteredAt: binary.BigEndian.Uint64(metadata),
+			TTL:          binary.BigEndian.Uint32(metadata[8:]),
+			Flags:        metadata[12],
+			Message:      value[messageStart : messageStart+messageLength : messageStart+messageLength],
+			Signature:    value[messageStart+messageLength : end : end],
+		})
+		i = end
+	}
+	return chain, nil
+}
+
+/*
+ * The bytes of the entry covered by its signature: the registration time, TTL, flags
+ * and message, as they appear in the encoded chain.
+ */
+func (e *Entry) SignedBytes() []byte {
+	signed := make([]byte, 0, EntryMetadataLength+len(e.Message))
+	signed = binary.BigEndian.AppendUint64(signed, e.RegisteredAt)
+	signed = binary.BigEndian.AppendUint32(signed, e.TTL)
+	signed = append(signed, e.Flags)
+	return append(signed, e.Message...)
+}
+
+/*
+ * Build the payload the entry's signature is computed over. It binds the entry to the
+ * file it is stored under so it cannot be replayed into the chain of another file.
+ *
+ * Parameters:
+ *   hash: The file hash the entry is stored under, i.e. the DHT key without its prefix
+ *
+ * Returns:
+ *   The domain tag, the length prefixed hash and the signed bytes of the entry
+ */
+func (e *Entry) SigningPayload(hash string) []byte {
+	return e.SigningPayloadWithDomain(SignatureDomain, hash)
+}
+
+/*
+ * Build the payload the entry's signature is computed over for a chain that is not a
+ * holder chain. Each kind of chain has its own domain tag, so an entry signed for one
+ * kind cannot be replayed into another.
+ *
+ * Parameters:
+ *   domain: The domain tag of the kind of chain, e.g. SignatureDomain
+ *   subject: What the chain is about, i.e. the DHT key without its prefix
+ *
+ * Returns:
+ *   The domain tag, the length prefixed subject and the signed bytes of the entry
+ */
+func (e *Entry) SigningPayloadWithDomain(domain string, subject string) []byte {
+	payload := make([]byte, 0, len(domain)+3+len(subject)+EntryMetadataLength+len(e.Message))
+	payload = append(payload, domain...)
+	payload = append(payload, 0)
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(subject)))
+	payload = append(payload, subject...)
+	return append(payload, e.SignedBytes()...)
+}
+
+/*
+ * Check whether the entry has outlived its TTL.
+ *
+ * Parameters:
+ *   now: The current unix time
+ *
+ * Returns:
+ *   True if the entry has expired
+ */
+func (e *Entry) Expired(now uint64) bool {
+	return e.RegisteredAt+uint64(e.TTL) < now
+}
+
+/*
+ * Drop all but the newest entries of a chain, keeping the rest in order. Entries are
+ * ordered by registration time, ties broken on the signature bytes, so every node keeps
+ * the same entries of the same chain.
+ *
+ * Parameters:
+ *   max: How many entries to keep
+ */
+func (c *Chain) KeepNewest(max int) {
+	if len(c.Entries) <= max {
+		return
+	}
+	order := make([]int, len(c.Entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := &c.Entries[order[i]], &c.Entries[order[j]]
+		if a.RegisteredAt != b.RegisteredAt {
+			return a.RegisteredAt > b.RegisteredAt
+		}
+		return bytes.Compare(a.Signature, b.Signature) > 0
+	})
+	keep := make([]bool, len(c.Entries))
+	for _, i := range order[:max] {
+		keep[i] = true
+	}
+	kept := make([]Entry, 0, max)
+	for i := range c.Entries {
+		if keep[i] {
+			kept = append(kept, c.Entries[i])
+		}
+	}
+	c.Entries = kept
+}
+
+// Whether the entry was left behind by its owner withdrawing the listing.
+func (e *Entry) Withdrawn() bool {
+	return e.Flags&FlagWithdrawn != 0
+}
+
+/*
+ * Find the shard of a holder chain an entry belongs in. Marshalled public keys of the
+ * same type share their first bytes, so entries are assigned by the prefix of the SHA-256
+ * digest of the key instead.
+ *
+ * Parameters:
+ *   id: The canonical id of the public key that signed the entry
+ *
+ * Returns:
+ *   The shard, below ShardCount
+ */
+func Shard(id []byte) int {
+	digest := sha256.Sum256(id)
+	//the leading bits of the digest pick the shard
+	return int(digest[0]) * ShardCount / 256
+}
@@ -0,0 +1,104 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Decoding arbitrary bytes must never panic, must fail only with the errors of this
+// package, and anything it accepts must encode back to the same bytes.
+func FuzzDecode(f *testing.F) {
+	valid, err := Encode(&Chain{Entries: []Entry{
+		{RegisteredAt: 1700000000, TTL: 3600, Message: []byte{0x0a, 0x01, 0x01}, Signature: []byte{1, 2, 3}},
+		{RegisteredAt: 1700000001, TTL: 60, Flags: FlagWithdrawn, Message: []byte{}, Signature: []byte{4}},
+	}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(valid)
+	f.Add(valid[:len(valid)-1])
+	f.Add(append(append([]byte{}, valid...), 0, 0))
+	f.Add([]byte{})
+	f.Add([]byte(Magic))
+	f.Add([]byte{'O', 'R', 'C', 'A', Version, 0xFF, 0xFF, 0xFF, 0xFF})
+
+	f.Fuzz(func(t *testing.T, value []byte) {
+		chain, err := Decode(value)
+		if err != nil {
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("error is not a *DecodeError: %v", err)
+			}
+			return
+		}
+		encoded, err := Encode(chain)
+		if err != nil {
+			t.Fatalf("decoded chain does not encode: %v", err)
+		}
+		if !bytes.Equal(encoded, value) {
+			t.Fatalf("round trip mismatch:\n got %x\nwant %x", encoded, value)
+		}
+	})
+}
+
+// Entries are assigned to shards by the first byte of the SHA-256 digest of their key.
+func TestShard(t *testing.T) {
+	tests := []struct {
+		id   []byte
+		want int
+	}{
+		//e3b0c442...
+		{[]byte{}, 14},
+		//ba7816bf...
+		{[]byte("abc"), 11},
+		//2c26b46b...
+		{[]byte("foo"), 2},
+	}
+	for _, test := range tests {
+		if got := Shard(test.id); got != test.want {
+			t.Errorf("Shard(%q) = %d, want %d", test.id, got, test.want)
+		}
+	}
+
+	seen := make([]bool, ShardCount)
+	for i := 0; i < 1024; i++ {
+		shard := Shard([]byte{byte(i), byte(i >> 8)})
+		if shard < 0 || shard >= ShardCount {
+			t.Fatalf("Shard of id %d is %d, outside [0, %d)", i, shard, ShardCount)
+		}
+		seen[shard] = true
+	}
+	for shard, ok := range seen {
+		if !ok {
+			t.Errorf("no id was assigned to shard %d", shard)
+		}
+	}
+}
+
+// KeepNewest keeps the newest entries, ties broken on the signature, in their order.
+func TestKeepNewest(t *testing.T) {
+	entry := func(registeredAt uint64, signature byte) Entry {
+		return Entry{RegisteredAt: registeredAt, Signature: []byte{signature}}
+	}
+	chain := &Chain{Entries: []Entry{entry(5, 1), entry(9, 1), entry(1, 1), entry(7, 2), entry(7, 3), entry(3, 1)}}
+	chain.KeepNewest(3)
+	want := []Entry{entry(9, 1), entry(7, 2), entry(7, 3)}
+	if len(chain.Entries) != len(want) {
+		t.Fatalf("kept %d entries, want %d", len(chain.Entries), len(want))
+	}
+	for i := range want {
+		if chain.Entries[i].RegisteredAt != want[i].RegisteredAt || !bytes.Equal(chain.Entries[i].Signature, want[i].Signature) {
+			t.Errorf("entry %d is %d/%x, want %d/%x", i, chain.Entries[i].RegisteredAt, chain.Entries[i].Signature, want[i].RegisteredAt, want[i].Signature)
+		}
+	}
+
+	chain.KeepNewest(2)
+	if len(chain.Entries) != 2 || chain.Entries[0].RegisteredAt != 9 || chain.Entries[1].Signature[0] != 3 {
+		t.Errorf("tie was not broken on the greater signature: %+v", chain.Entries)
+	}
+	chain.KeepNewest(5)
+	if len(chain.Entries) != 2 {
+		t.Errorf("a chain within the limit lost entries: %+v", chain.Entries)
+	}
+}
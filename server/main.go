@@ -30,42 +30,65 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
-	"net"
-	"sync"
-	pb "orcanet/market"
 	"github.com/libp2p/go-libp2p"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
-	record "github.com/libp2p/go-libp2p-record"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/peer"
-	"github.com/multiformats/go-multiaddr"
+	"github.com/libp2p/go-libp2p/core/protocol"
 	"google.golang.org/grpc"
-	"orcanet/util"
+	"log"
+	"net"
+	"orcanet/config"
 	"orcanet/market"
+	pb "orcanet/market"
+	"orcanet/store"
+	"orcanet/util"
 	"orcanet/validator"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
-var (
-	port = flag.Int("port", 50051, "The server port")
+var settings = config.Bind(flag.CommandLine, config.Default())
+
+const (
+	// How long to wait for in-flight calls on shutdown before cancelling open watches
+	shutdownGracePeriod = 10 * time.Second
 )
 
 func main() {
 	flag.Parse()
 	ctx := context.Background()
 
-	//Generate or load private key for libp2p host, 
-	privKey, err := util.CheckOrCreatePrivateKey("privateKey.pem");
-	if(err != nil){
-		panic(err);
+	cfg, err := settings.Load()
+	if err != nil {
+		panic(err)
+	}
+
+	//Generate or load private key for libp2p host,
+	keyType, err := util.ParseKeyType(cfg.KeyType)
+	if err != nil {
+		panic(err)
+	}
+	privKey, err := util.CheckOrCreatePrivateKey(cfg.KeyPath, keyType)
+	if err != nil {
+		panic(err)
 	}
 
-	pubKey := privKey.GetPublic();
+	pubKey := privKey.GetPublic()
+
+	storageMode, err := market.ParseStorageMode(cfg.Market.Storage)
+	if err != nil {
+		panic(err)
+	}
 
-	//Construct multiaddr from string and create host to listen on it
-	sourceMultiAddr, _ := multiaddr.NewMultiaddr("/ip4/0.0.0.0/tcp/44981")
+	//Create host to listen on the configured multiaddrs
 	opts := []libp2p.Option{
-		libp2p.ListenAddrStrings(sourceMultiAddr.String()),
-		libp2p.Identity(privKey), //derive id from private key
+		libp2p.ListenAddrStrings(cfg.ListenAddrs...),
+		libp2p.Identity(privKey),  //derive id from private key
+		libp2p.EnableNATService(), //dial back peers so they can tell whether they are reachable
 	}
 	host, err := libp2p.New(opts...)
 	if err != nil {
@@ -77,18 +100,38 @@ func main() {
 		log.Printf("%s/p2p/%s", addr, host.ID())
 	}
 
-	bootstrapPeers := util.ReadBootstrapPeers()
+	bootstrapPeers, err := cfg.BootstrapPeers()
+	if err != nil {
+		panic(err)
+	}
+	dhtMode, err := config.ParseDHTMode(cfg.DHT.Mode)
+	if err != nil {
+		panic(err)
+	}
 
-	// Start a DHT, for now we will start in client mode until we can implement a way to 
-	// detect if we are behind a NAT or not to run in server mode.
-	var validator record.Validator = validator.OrcaValidator{}
+	// Start a DHT. In the default auto mode it runs as a client until AutoNAT finds the host
+	// publicly reachable, then serves records like a bootstrap node, see util.FollowReachability.
+	namespaces := validator.NewOrcaNamespaces()
+	datastore, err := store.Open(cfg.Datastore.Kind, cfg.Datastore.Path)
+	if err != nil {
+		panic(err)
+	}
 	var options []dht.Option
-	options = append(options, dht.Mode(dht.ModeClient))
-	options = append(options, dht.ProtocolPrefix("orcanet/market"), dht.Validator(validator))
+	options = append(options, dht.Mode(dhtMode))
+	options = append(options, dht.ProtocolPrefix(protocol.ID(cfg.DHT.ProtocolPrefix)), dht.Validator(namespaces))
+	options = append(options, dht.Datastore(datastore), dht.MaxRecordAge(store.MaxRecordAge))
 	kDHT, err := dht.New(ctx, host, options...)
 	if err != nil {
 		panic(err)
 	}
+	collector := store.NewCollector(datastore, namespaces, cfg.Datastore.GCInterval, store.MaxRecordAge)
+	collector.Start(ctx)
+
+	//Registrations are kept apart from the DHT records, which the collector prunes
+	registryStore, err := store.Open(store.LevelDB, cfg.RegistryPath)
+	if err != nil {
+		panic(err)
+	}
 
 	// Bootstrap the DHT. In the default configuration, this spawns a Background
 	// thread that will refresh the peer table every five minutes.
@@ -114,25 +157,78 @@ func main() {
 	}
 	wg.Wait()
 
-	go util.DiscoverPeers(ctx, host, kDHT, "orcanet/market")
+	//nodes of a network find each other under its protocol prefix, so separate networks stay apart
+	go util.DiscoverPeers(ctx, host, kDHT, cfg.DHT.ProtocolPrefix)
+
+	//Gossip new registrations to the peers we discover
+	ps, err := pubsub.NewGossipSub(ctx, host)
+	if err != nil {
+		panic(err)
+	}
+	announcer, err := market.NewAnnouncer(ps, market.AnnounceTopic(cfg.DHT.ProtocolPrefix), namespaces)
+	if err != nil {
+		panic(err)
+	}
+	announcer.Start(ctx)
 
 	//Start gRPC server
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
 	if err != nil {
 		panic(err)
 	}
 
 	s := grpc.NewServer()
 	serverStruct := market.Server{}
-	serverStruct.K_DHT = kDHT;
-	serverStruct.PrivKey = privKey;
-	serverStruct.PubKey = pubKey;
-	serverStruct.V = validator
+	serverStruct.K_DHT = kDHT
+	serverStruct.PrivKey = privKey
+	serverStruct.PubKey = pubKey
+	serverStruct.V = namespaces
+	serverStruct.WatchInterval = cfg.Market.WatchInterval
+	serverStruct.Storage = storageMode
+	serverStruct.BatchParallelism = cfg.Market.BatchParallelism
+	serverStruct.Prober = market.NewProber(cfg.Market.Probe.Timeout, cfg.Market.Probe.CacheTTL)
+	serverStruct.Prober.AllowPrivate = cfg.Market.Probe.Private
+	serverStruct.Announcer = announcer
+	serverStruct.Republisher = market.NewRepublisher(&serverStruct, cfg.Market.RepublishInterval)
+	serverStruct.Republisher.Start(ctx)
+	serverStruct.Registry = market.NewRegistry(registryStore)
 	pb.RegisterMarketServer(s, &serverStruct)
+
+	//Put back the listings of files registered before a restart that have since lapsed
+	reconcileCtx, cancelReconcile := context.WithCancel(ctx)
+	reconciled := make(chan struct{})
+	go func() {
+		defer close(reconciled)
+		if err := serverStruct.Reconcile(reconcileCtx); err != nil {
+			log.Printf("Failed to reconcile registrations: %v", err)
+		}
+	}()
+
+	// Shut down cleanly on interrupt so in-flight DHT puts are not cut off mid-write
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Shutting down")
+		cancelReconcile()
+		<-reconciled
+		serverStruct.Republisher.Stop()
+		//ends open SubscribeAnnouncements streams
+		announcer.Stop()
+		//WatchHolders streams only end when cancelled, so force them closed after a grace period
+		stopped := time.AfterFunc(shutdownGracePeriod, s.Stop)
+		s.GracefulStop()
+		stopped.Stop()
+	}()
+
 	log.Printf("Server listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("Error %v", err)
 	}
 
-	select {}
-}
\ No newline at end of file
+	collector.Stop()
+	kDHT.Close()
+	host.Close()
+	datastore.Close()
+	registryStore.Close()
+}
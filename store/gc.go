@@ -0,0 +1,193 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	p2precord "github.com/libp2p/go-libp2p-record"
+	recpb "github.com/libp2p/go-libp2p-record/pb"
+)
+
+const (
+	// How often expired records are removed when the node does not set an interval.
+	DefaultCollectInterval = time.Hour
+	// How long the DHT keeps a record after it was last put, set with dht.MaxRecordAge.
+	// Market servers republish their records well within it.
+	MaxRecordAge = 48 * time.Hour
+)
+
+// A record the collector removes.
+type stale struct {
+	key ds.Key
+	// The record as it was read, it is left alone if the DHT has replaced it since
+	original []byte
+}
+
+/*
+ * Removes the records a persistent datastore holds once they expire. The DHT only drops
+ * a record that has not been put again within its maximum age when it is asked for, so
+ * without collection records nobody asks for would pile up on disk across restarts.
+ * Expired entries inside a live chain are left to the market servers, which prune them
+ * whenever they write the chain. Provider records are collected by the DHT itself and
+ * left alone.
+ */
+type Collector struct {
+	datastore ds.Batching
+	validator p2precord.Validator
+	interval  time.Duration
+	maxAge    time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+/*
+ * Create a collector for the records the DHT keeps in a datastore.
+ *
+ * Parameters:
+ *   datastore: The datastore given to the DHT
+ *   validator: The validator given to the DHT
+ *   interval: How often to collect, DefaultCollectInterval if zero
+ *   maxAge: How long the DHT keeps a record after it was put, as set with dht.MaxRecordAge
+ *
+ * Returns:
+ *   A collector, which does nothing until Start is called
+ */
+func NewCollector(datastore ds.Batching, validator p2precord.Validator, interval time.Duration, maxAge time.Duration) *Collector {
+	if interval <= 0 {
+		interval = DefaultCollectInterval
+	}
+	return &Collector{
+		datastore: datastore,
+		validator: validator,
+		interval:  interval,
+		maxAge:    maxAge,
+	}
+}
+
+/*
+ * Collect right away, then every interval in the background until Stop is called or ctx
+ * is done. Records left over from before a restart are collected on the first pass.
+ *
+ * Parameters:
+ *   ctx: Context
+ */
+func (c *Collector) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.done = make(chan struct{})
+	go c.run(ctx)
+}
+
+// Stop collecting, aborting any collection in progress, and wait for it to exit.
+func (c *Collector) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
+
+func (c *Collector) run(ctx context.Context) {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Collect(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to collect expired DHT records: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+ * Make one pass over the datastore, removing records that are too old or no longer valid.
+ * A pass cancelled partway stops where it is, the records it did not get to are removed
+ * on the next pass.
+ *
+ * Parameters:
+ *   ctx: Context
+ *
+ * Returns:
+ *   An error, if the datastore could not be read or written or ctx was done
+ */
+func (c *Collector) Collect(ctx context.Context) error {
+	results, err := c.datastore.Query(ctx, query.Query{})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	stales := make([]stale, 0)
+	for result := range results.Next() {
+		if result.Error != nil {
+			results.Close()
+			return result.Error
+		}
+		if err := ctx.Err(); err != nil {
+			results.Close()
+			return err
+		}
+		//records are stored under a single base32 segment, provider records under /providers/...
+		key := ds.NewKey(result.Key)
+		if len(key.Namespaces()) != 1 {
+			continue
+		}
+		if c.expired(result.Value, now) {
+			stales = append(stales, stale{key: key, original: result.Value})
+		}
+	}
+	if err := results.Close(); err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, st := range stales {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		current, err := c.datastore.Get(ctx, st.key)
+		if err == ds.ErrNotFound || (err == nil && !bytes.Equal(current, st.original)) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.datastore.Delete(ctx, st.key); err != nil {
+			return err
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		log.Printf("Collected DHT records: %d deleted", deleted)
+	}
+	return nil
+}
+
+/*
+ * Decide whether a stored record should be removed.
+ *
+ * Parameters:
+ *   data: The record as stored by the DHT
+ *   now: The current time
+ *
+ * Returns:
+ *   True if the record is malformed, invalid or was last put more than maxAge ago
+ */
+func (c *Collector) expired(data []byte, now time.Time) bool {
+	rec := &recpb.Record{}
+	if err := rec.Unmarshal(data); err != nil {
+		return true
+	}
+	received, err := time.Parse(time.RFC3339Nano, rec.GetTimeReceived())
+	if err != nil || now.Sub(received) > c.maxAge {
+		return true
+	}
+	return c.validator.Validate(string(rec.GetKey()), rec.GetValue()) != nil
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	dssync "github.com/ipfs/go-datastore/sync"
+	recpb "github.com/libp2p/go-libp2p-record/pb"
+)
+
+// Accepts every value except "invalid".
+type testValidator struct{}
+
+func (testValidator) Validate(key string, value []byte) error {
+	if string(value) == "invalid" {
+		return errors.New("invalid value")
+	}
+	return nil
+}
+
+func (testValidator) Select(key string, values [][]byte) (int, error) {
+	return 0, nil
+}
+
+// Store a record under key the way the DHT does, as received at the given time.
+func putRecord(t *testing.T, datastore ds.Datastore, key string, value string, received time.Time) ds.Key {
+	t.Helper()
+	rec := &recpb.Record{Key: []byte(key), Value: []byte(value), TimeReceived: received.Format(time.RFC3339Nano)}
+	data, err := rec.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsKey := ds.NewKey(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(key)))
+	if err := datastore.Put(context.Background(), dsKey, data); err != nil {
+		t.Fatal(err)
+	}
+	return dsKey
+}
+
+// Whether each key is still stored.
+func stored(t *testing.T, datastore ds.Datastore, keys ...ds.Key) []bool {
+	t.Helper()
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		has, err := datastore.Has(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[i] = has
+	}
+	return found
+}
+
+// Records past their maximum age or no longer valid are removed, anything else is left.
+func TestCollect(t *testing.T) {
+	datastore := dssync.MutexWrap(ds.NewMapDatastore())
+	now := time.Now()
+	tests := []struct {
+		name string
+		key  ds.Key
+		keep bool
+	}{
+		{"live record", putRecord(t, datastore, "orcanet/market/live", "chain", now.Add(-time.Hour)), true},
+		{"record just within its age", putRecord(t, datastore, "orcanet/market/old", "chain", now.Add(-MaxRecordAge+time.Minute)), true},
+		{"record that is not a chain", putRecord(t, datastore, "/pk/peer", "public key", now), true},
+		{"expired record", putRecord(t, datastore, "orcanet/market/expired", "chain", now.Add(-MaxRecordAge-time.Minute)), false},
+		{"invalid record", putRecord(t, datastore, "orcanet/market/invalid", "invalid", now), false},
+		{"record without a receive time", putRecord(t, datastore, "orcanet/market/untimed", "chain", time.Time{}), false},
+	}
+	malformed := ds.NewKey("bm9uc2Vuc2U")
+	provider := ds.NewKey("/providers/ciqabc/peer")
+	for _, key := range []ds.Key{malformed, provider} {
+		if err := datastore.Put(context.Background(), key, []byte("not a record")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewCollector(datastore, testValidator{}, 0, MaxRecordAge).Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if found := stored(t, datastore, test.key)[0]; found != test.keep {
+			t.Errorf("%s: stored %v after collecting, want %v", test.name, found, test.keep)
+		}
+	}
+	if found := stored(t, datastore, malformed, provider); !found[1] || found[0] {
+		t.Errorf("malformed record and provider record stored %v after collecting, want [false true]", found)
+	}
+}
+
+// Cancels its context once the collector has done enough: read after entries while
+// scanning, or deleted after records.
+type cancellingDatastore struct {
+	ds.Batching
+	cancel  context.CancelFunc
+	read    int
+	deleted int
+}
+
+func (d *cancellingDatastore) Query(ctx context.Context, q query.Query) (query.Results, error) {
+	results, err := d.Batching.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	i := 0
+	return query.ResultsFromIterator(q, query.Iterator{
+		Next: func() (query.Result, bool) {
+			if i >= len(entries) {
+				return query.Result{}, false
+			}
+			if i == d.read {
+				d.cancel()
+			}
+			i++
+			return query.Result{Entry: entries[i-1]}, true
+		},
+		Close: func() error { return nil },
+	}), nil
+}
+
+func (d *cancellingDatastore) Delete(ctx context.Context, key ds.Key) error {
+	if err := d.Batching.Delete(ctx, key); err != nil {
+		return err
+	}
+	d.deleted--
+	if d.deleted == 0 {
+		d.cancel()
+	}
+	return nil
+}
+
+// A pass cancelled partway stops and leaves the records it did not get to for the next.
+func TestCollectCancelled(t *testing.T) {
+	tests := []struct {
+		name    string
+		read    int
+		deleted int
+		// How many of the expired records the cancelled pass removes
+		want int
+	}{
+		{"before scanning", 0, -1, 0},
+		{"while scanning", 2, -1, 0},
+		{"while deleting", -1, 1, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := dssync.MutexWrap(ds.NewMapDatastore())
+			expired := []ds.Key{
+				putRecord(t, inner, "orcanet/market/a", "chain", time.Now().Add(-2*MaxRecordAge)),
+				putRecord(t, inner, "orcanet/market/b", "chain", time.Now().Add(-2*MaxRecordAge)),
+				putRecord(t, inner, "orcanet/market/c", "invalid", time.Now()),
+			}
+			live := putRecord(t, inner, "orcanet/market/d", "chain", time.Now())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			datastore := &cancellingDatastore{Batching: inner, cancel: cancel, read: test.read, deleted: test.deleted}
+			collector := NewCollector(datastore, testValidator{}, 0, MaxRecordAge)
+			if err := collector.Collect(ctx); !errors.Is(err, context.Canceled) {
+				t.Fatalf("cancelled Collect returned %v, want context.Canceled", err)
+			}
+			removed := 0
+			for _, found := range stored(t, inner, expired...) {
+				if !found {
+					removed++
+				}
+			}
+			if removed != test.want {
+				t.Errorf("cancelled pass removed %d expired records, want %d", removed, test.want)
+			}
+
+			if err := collector.Collect(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			found := stored(t, inner, append(expired, live)...)
+			for i, key := range expired {
+				if found[i] {
+					t.Errorf("%s is still stored after the next pass", key)
+				}
+			}
+			if !found[len(expired)] {
+				t.Error("the live record was removed")
+			}
+		})
+	}
+}
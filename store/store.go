@@ -0,0 +1,47 @@
+/*
+ * Persistent datastores for the DHT, so the records a node stores for the network
+ * survive restarts, and a collector that removes records once they expire.
+ */
+package store
+
+import (
+	"fmt"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	badger "github.com/ipfs/go-ds-badger"
+	leveldb "github.com/ipfs/go-ds-leveldb"
+)
+
+// Names of the datastores Open can create.
+const (
+	// Kept in memory and lost on restart.
+	Memory = "memory"
+	// LevelDB database in a directory.
+	LevelDB = "leveldb"
+	// Badger database in a directory.
+	Badger = "badger"
+)
+
+/*
+ * Open the datastore the DHT keeps its records in.
+ *
+ * Parameters:
+ *   kind: Memory, LevelDB or Badger
+ *   path: The directory of an on-disk datastore, created if it does not exist
+ *
+ * Returns:
+ *   The datastore, to be closed once the DHT is
+ *   An error, if the kind is not known or the datastore could not be opened
+ */
+func Open(kind string, path string) (ds.Batching, error) {
+	switch kind {
+	case Memory:
+		return dssync.MutexWrap(ds.NewMapDatastore()), nil
+	case LevelDB:
+		return leveldb.NewDatastore(path, nil)
+	case Badger:
+		return badger.NewDatastore(path, &badger.DefaultOptions)
+	}
+	return nil, fmt.Errorf("unknown datastore %q, expected memory, leveldb or badger", kind)
+}
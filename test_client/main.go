@@ -20,18 +20,20 @@
 package main
 
 import (
-	"cmp"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"slices"
 	"time"
 
 	pb "orcanet/market"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 var (
@@ -67,7 +69,7 @@ func main() {
 
 	// Create a User struct with the provided username and generated ID
 	user := &pb.User{
-		Name: username,
+		Name:  username,
 		Ip:    "localhost2",
 		Port:  416320,
 		Price: price,
@@ -77,7 +79,11 @@ func main() {
 		fmt.Println("---------------------------------")
 		fmt.Println("1. Register a file")
 		fmt.Println("2. Check holders for a file")
-		fmt.Println("3. Exit")
+		fmt.Println("3. Unregister a file")
+		fmt.Println("4. Watch holders for a file")
+		fmt.Println("5. Watch announcements of new files")
+		fmt.Println("6. List registrations")
+		fmt.Println("7. Exit")
 		fmt.Print("Option: ")
 		var choice int
 		_, err := fmt.Scanln(&choice)
@@ -86,9 +92,19 @@ func main() {
 			continue
 		}
 
-		if choice == 3 {
+		if choice == 7 {
 			return
 		}
+		if choice == 6 {
+			listRegistrations(c)
+			fmt.Println()
+			continue
+		}
+		if choice == 5 {
+			watchAnnouncements(c)
+			fmt.Println()
+			continue
+		}
 
 		fmt.Print("Enter a file hash: ")
 		var fileHash string
@@ -104,7 +120,9 @@ func main() {
 		case 2:
 			checkHolders(c, user, fileHash)
 		case 3:
-			return
+			unregisterFile(c, fileHash)
+		case 4:
+			watchHolders(c, fileHash)
 		default:
 			fmt.Println("Unknown option: ", choice)
 		}
@@ -121,15 +139,12 @@ func checkHolders(c pb.MarketClient, user *pb.User, fileHash string) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	holders, err := c.CheckHolders(ctx, &pb.CheckHoldersRequest{FileHash: fileHash})
+	holders, err := c.CheckHolders(ctx, &pb.CheckHoldersRequest{FileHash: fileHash, Sort: pb.CheckHoldersRequest_PRICE})
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 		return
 	}
-	supply_files := holders.GetHolders();
-	slices.SortFunc(supply_files, func(a, b *pb.User) int {
-		return cmp.Compare(a.GetPrice(), b.GetPrice())
-	})
+	supply_files := holders.GetHolders()
 	for idx, holder := range supply_files {
 		fmt.Printf("(%d), Name: %s, Price: %d\n", idx, holder.GetName(), holder.GetPrice())
 	}
@@ -146,3 +161,86 @@ func registerFile(c pb.MarketClient, user *pb.User, fileHash string) {
 		log.Printf("Success")
 	}
 }
+
+func unregisterFile(c pb.MarketClient, fileHash string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := c.UnregisterFile(ctx, &pb.UnregisterFileRequest{FileHash: fileHash})
+	if status.Code(err) == codes.NotFound {
+		log.Printf("Not registered: %v", status.Convert(err).Message())
+	} else if err != nil {
+		log.Fatalf("Error: %v", err)
+	} else {
+		log.Printf("Success")
+	}
+}
+
+// print changes to the holders of a file until enter is pressed
+func watchHolders(c pb.MarketClient, fileHash string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.WatchHolders(ctx, &pb.CheckHoldersRequest{FileHash: fileHash})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Println("Watching, press enter to stop")
+	go func() {
+		fmt.Scanln()
+		cancel()
+	}()
+
+	for {
+		event, err := stream.Recv()
+		if status.Code(err) == codes.Canceled || err == io.EOF {
+			return
+		} else if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		holder := event.GetHolder()
+		fmt.Printf("%s: Name: %s, Price: %d\n", event.GetType(), holder.GetName(), holder.GetPrice())
+	}
+}
+
+// print the files registered on the network until enter is pressed
+func watchAnnouncements(c pb.MarketClient) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := c.SubscribeAnnouncements(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	fmt.Println("Watching, press enter to stop")
+	go func() {
+		fmt.Scanln()
+		cancel()
+	}()
+
+	for {
+		announcement, err := stream.Recv()
+		if status.Code(err) == codes.Canceled || err == io.EOF {
+			return
+		} else if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		holder := announcement.GetHolder()
+		fmt.Printf("%s: Name: %s, Price: %d\n", announcement.GetFileHash(), holder.GetName(), holder.GetPrice())
+	}
+}
+
+// print the files the server has registered
+func listRegistrations(c pb.MarketClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	res, err := c.ListRegistrations(ctx, &emptypb.Empty{})
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	for _, registration := range res.GetRegistrations() {
+		lastPut := time.Unix(int64(registration.GetLastPut()), 0)
+		fmt.Printf("%s: Price: %d, Last put: %s\n", registration.GetFileHash(), registration.GetUser().GetPrice(), lastPut.Format(time.RFC3339))
+	}
+}
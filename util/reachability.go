@@ -0,0 +1,100 @@
+package util
+
+import (
+	"context"
+	"log"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
+	dutil "github.com/libp2p/go-libp2p/p2p/discovery/util"
+)
+
+/*
+ * Tell whether a DHT in the given mode serves records at the given reachability. A DHT
+ * in an auto mode switches to server mode once AutoNAT finds the host publicly reachable
+ * and back to client mode once it finds it private.
+ *
+ * Parameters:
+ *   mode: The mode the DHT was started in
+ *   reachability: The reachability AutoNAT last reported for the host
+ *
+ * Returns:
+ *   True if the DHT runs in server mode
+ */
+func ServesDHT(mode dht.ModeOpt, reachability network.Reachability) bool {
+	switch mode {
+	case dht.ModeServer:
+		return true
+	case dht.ModeAuto:
+		return reachability == network.ReachabilityPublic
+	case dht.ModeAutoServer:
+		return reachability != network.ReachabilityPrivate
+	}
+	return false
+}
+
+/*
+ * Follow the reachability AutoNAT finds for a host whose DHT runs in an auto mode. Every
+ * change is logged along with the mode the DHT switches to, and the host is advertised
+ * for peer discovery while the DHT runs in server mode, so other nodes only find peers
+ * that serve records. Returns once ctx is done.
+ *
+ * Parameters:
+ *   ctx: The context
+ *   h: libp2p host, with AutoNAT enabled
+ *   kDHT: the DHT, started in dht.ModeAuto or dht.ModeAutoServer
+ *   advertise: the string to announce ourselves under while the DHT serves records
+ */
+func FollowReachability(ctx context.Context, h host.Host, kDHT *dht.IpfsDHT, advertise string) {
+	//AutoNAT emits the current reachability to new subscribers, so no change is missed
+	sub, err := h.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		log.Printf("Failed to follow reachability: %v", err)
+		return
+	}
+	defer sub.Close()
+
+	routingDiscovery := drouting.NewRoutingDiscovery(kDHT)
+	//closed to stop advertising, nil while not advertising
+	var stopAdvertising chan struct{}
+
+	previous := network.ReachabilityUnknown
+	for {
+		var e interface{}
+		select {
+		case <-ctx.Done():
+			return
+		case e = <-sub.Out():
+		}
+		reachability := e.(event.EvtLocalReachabilityChanged).Reachability
+		serving := ServesDHT(kDHT.Mode(), reachability)
+		mode := "client"
+		if serving {
+			mode = "server"
+		}
+		log.Printf("Reachability changed from %s to %s, the DHT runs in %s mode", previous, reachability, mode)
+		previous = reachability
+
+		if serving && stopAdvertising == nil {
+			stopAdvertising = make(chan struct{})
+			go advertiseUntil(ctx, stopAdvertising, routingDiscovery, advertise)
+		} else if !serving && stopAdvertising != nil {
+			close(stopAdvertising)
+			stopAdvertising = nil
+		}
+	}
+}
+
+// Advertise ourselves under a rendezvous string until stop is closed or ctx is done.
+func advertiseUntil(ctx context.Context, stop <-chan struct{}, routingDiscovery *drouting.RoutingDiscovery, advertise string) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	dutil.Advertise(ctx, routingDiscovery, advertise)
+	select {
+	case <-ctx.Done():
+	case <-stop:
+	}
+}
@@ -0,0 +1,36 @@
+package util
+
+import (
+	"testing"
+
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/libp2p/go-libp2p/core/network"
+)
+
+// A DHT in an auto mode serves records only once AutoNAT finds the host public, or for
+// ModeAutoServer until it finds it private. Fixed modes ignore reachability.
+func TestServesDHT(t *testing.T) {
+	tests := []struct {
+		mode    dht.ModeOpt
+		name    string
+		public  bool
+		private bool
+		unknown bool
+	}{
+		{dht.ModeAuto, "auto", true, false, false},
+		{dht.ModeAutoServer, "auto-server", true, false, true},
+		{dht.ModeServer, "server", true, true, true},
+		{dht.ModeClient, "client", false, false, false},
+	}
+	for _, test := range tests {
+		for reachability, want := range map[network.Reachability]bool{
+			network.ReachabilityPublic:  test.public,
+			network.ReachabilityPrivate: test.private,
+			network.ReachabilityUnknown: test.unknown,
+		} {
+			if got := ServesDHT(test.mode, reachability); got != want {
+				t.Errorf("ServesDHT(%s, %s) = %v, want %v", test.name, reachability, got, want)
+			}
+		}
+	}
+}
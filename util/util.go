@@ -9,67 +9,95 @@ package util
  */
 
 import (
+	"bufio"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
-	"io/ioutil"
-	"os"
-	"log"
 	"errors"
+	"fmt"
+	"github.com/ipfs/go-cid"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
 	crypto "github.com/libp2p/go-libp2p/core/crypto"
 	host "github.com/libp2p/go-libp2p/core/host"
-	dht "github.com/libp2p/go-libp2p-kad-dht"
-	"github.com/multiformats/go-multiaddr"
 	drouting "github.com/libp2p/go-libp2p/p2p/discovery/routing"
 	dutil "github.com/libp2p/go-libp2p/p2p/discovery/util"
-	"fmt"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multihash"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
 	"time"
-	"bufio"
 )
 
+// PEM block type for keys stored in libp2p's own marshalled format. Used for every key
+// type except RSA, which is stored as PKCS #1 so files written by older versions still load.
+const libp2pKeyPEMType = "LIBP2P PRIVATE KEY"
+
 /*
  *
- * Check a file for a 2048 bit RSA private key and load it or generate a new one.
+ * Check a file for a private key and load it or generate a new one. RSA keys are stored as
+ * PKCS #1 PEM, Ed25519 and secp256k1 keys as libp2p marshalled keys in PEM.
  *
  * Parameters:
  *   path: The name of the file to load key from or file name to save new key to.
+ *   keyType: The libp2p key type (crypto.RSA, crypto.Ed25519 or crypto.Secp256k1) to
+ *            generate if the file does not exist. An existing key is loaded whatever its type.
  *
  * Returns:
  *   If the file exists and is of correct format, a libp2p wrapped private key is returned.
  *   If the file does not exist, a new one is generated and saved to the specified file name.
- *   If the specified file exists but does not contain a valid RSA private key, an error is returned.
+ *   If the specified file exists but does not contain a valid private key, an error is returned.
  *   Returns an error for any key generation error.
- * 
- * Author: Rushikesh 
+ *
+ * Author: Rushikesh
  */
-func CheckOrCreatePrivateKey(path string) (crypto.PrivKey, error) {
+func CheckOrCreatePrivateKey(path string, keyType int) (crypto.PrivKey, error) {
 	// Check if the privateKey.pem exists
 	_, err := os.Stat(path)
 	if os.IsNotExist(err) {
 		// No private key file, so let's create one
-		privKey, err := rsa.GenerateKey(rand.Reader, 2048)
-		if err != nil {
-			return nil, err
+		var privKeyPEM []byte
+		var libp2pPrivKey crypto.PrivKey
+		if keyType == crypto.RSA {
+			privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				return nil, err
+			}
+			privKeyBytes := x509.MarshalPKCS1PrivateKey(privKey)
+			privKeyPEM = pem.EncodeToMemory(&pem.Block{
+				Type:  "RSA PRIVATE KEY",
+				Bytes: privKeyBytes,
+			})
+			libp2pPrivKey, _, err = crypto.KeyPairFromStdKey(privKey)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			libp2pPrivKey, _, err = crypto.GenerateKeyPairWithReader(keyType, 0, rand.Reader)
+			if err != nil {
+				return nil, err
+			}
+			privKeyBytes, err := crypto.MarshalPrivateKey(libp2pPrivKey)
+			if err != nil {
+				return nil, err
+			}
+			privKeyPEM = pem.EncodeToMemory(&pem.Block{
+				Type:  libp2pKeyPEMType,
+				Bytes: privKeyBytes,
+			})
 		}
-		privKeyBytes := x509.MarshalPKCS1PrivateKey(privKey)
-		privKeyPEM := pem.EncodeToMemory(&pem.Block{
-			Type:  "RSA PRIVATE KEY",
-			Bytes: privKeyBytes,
-		})
 		err = ioutil.WriteFile(path, privKeyPEM, 0600)
 		if err != nil {
 			return nil, err
 		}
-		log.Println("New private key generated and saved to", path)
-
-		libp2pPrivKey, _, err := crypto.KeyPairFromStdKey(privKey);
-		if(err != nil){
-			return nil, err
-		}
+		log.Printf("New %s private key generated and saved to %s", libp2pPrivKey.Type(), path)
 
-		return libp2pPrivKey, nil;
+		return libp2pPrivKey, nil
 	} else if err != nil {
 		// Some other error occurred when trying to read the file
 		return nil, err
@@ -81,28 +109,236 @@ func CheckOrCreatePrivateKey(path string) (crypto.PrivKey, error) {
 		return nil, err
 	}
 	block, _ := pem.Decode(privKeyBytes)
-	if block == nil || block.Type != "RSA PRIVATE KEY" {
+	if block == nil || (block.Type != "RSA PRIVATE KEY" && block.Type != libp2pKeyPEMType) {
 		log.Println("Private key file is of invalid format")
 		return nil, errors.New("private key file is of invalid format")
 	}
-	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
+
+	var libp2pPrivKey crypto.PrivKey
+	if block.Type == libp2pKeyPEMType {
+		libp2pPrivKey, err = crypto.UnmarshalPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		libp2pPrivKey, _, err = crypto.KeyPairFromStdKey(privKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("Existing %s private key loaded from %s", libp2pPrivKey.Type(), path)
+
+	return libp2pPrivKey, nil
+}
+
+/*
+ * Parse the name of a key type, as given on the command line.
+ *
+ * Parameters:
+ *   name: One of "rsa", "ed25519" or "secp256k1", in any case
+ *
+ * Returns:
+ *   The libp2p key type, e.g. crypto.Ed25519
+ *   An error, if the name is not a supported key type
+ */
+func ParseKeyType(name string) (int, error) {
+	switch strings.ToLower(name) {
+	case "rsa":
+		return crypto.RSA, nil
+	case "ed25519":
+		return crypto.Ed25519, nil
+	case "secp256k1":
+		return crypto.Secp256k1, nil
+	}
+	return 0, fmt.Errorf("unsupported key type %q, expected rsa, ed25519 or secp256k1", name)
+}
+
+/*
+ * Encode a public key for the id field of a User. The libp2p marshalled form is used,
+ * which tags the key bytes with their key type.
+ *
+ * Parameters:
+ *   pubKey: The public key to encode
+ *
+ * Returns:
+ *   The encoded id
+ *   An error, if any
+ */
+func MarshalUserID(pubKey crypto.PubKey) ([]byte, error) {
+	return crypto.MarshalPublicKey(pubKey)
+}
+
+/*
+ * Decode the id field of a User into a public key. Besides the libp2p marshalled form
+ * written by MarshalUserID, the raw PKIX bytes of an RSA key used by older records are
+ * accepted.
+ *
+ * Parameters:
+ *   id: The id field of a User
+ *
+ * Returns:
+ *   The public key
+ *   An error, if the id is not a supported public key
+ */
+func UnmarshalUserID(id []byte) (crypto.PubKey, error) {
+	pubKey, err := crypto.UnmarshalPublicKey(id)
+	if err == nil {
+		return pubKey, nil
+	}
+	pubKey, rsaErr := crypto.UnmarshalRsaPublicKey(id)
+	if rsaErr != nil {
 		return nil, err
 	}
-	log.Println("Existing private key loaded from", path)
+	return pubKey, nil
+}
 
-	libp2pPrivKey, _, err := crypto.KeyPairFromStdKey(privKey);
-	if(err != nil){
+/*
+ * Re-encode the id field of a User in the form written by MarshalUserID, so ids of the
+ * same key compare equal whether or not they use the older RSA encoding.
+ *
+ * Parameters:
+ *   id: The id field of a User
+ *
+ * Returns:
+ *   The canonical id
+ *   An error, if the id is not a supported public key
+ */
+func CanonicalUserID(id []byte) ([]byte, error) {
+	pubKey, err := UnmarshalUserID(id)
+	if err != nil {
 		return nil, err
 	}
+	return MarshalUserID(pubKey)
+}
 
-	return libp2pPrivKey, nil;
+// Matches a hex SHA-256 digest, in either case.
+var hexFileHashPattern = regexp.MustCompile("^[a-fA-F0-9]{64}$")
+
+// Digest length in bytes of each hash function files may be keyed by.
+var fileHashDigestLengths = map[uint64]int{
+	multihash.SHA2_256:         32,
+	multihash.SHA2_512:         64,
+	multihash.SHA3_224:         28,
+	multihash.SHA3_256:         32,
+	multihash.SHA3_384:         48,
+	multihash.SHA3_512:         64,
+	multihash.KECCAK_256:       32,
+	multihash.KECCAK_512:       64,
+	multihash.BLAKE3:           32,
+	multihash.BLAKE2B_MIN + 31: 32,
+	multihash.BLAKE2B_MAX:      64,
+	multihash.BLAKE2S_MAX:      32,
 }
 
 /*
- * Check for peers who have announced themselves on the DHT. 
- * If the DHT is running in server mode, then we will announce ourselves and check for 
- * others who have announced as well.
+ * Normalize a file hash to the form it is stored under on the DHT. A file may be given as
+ * a hex SHA-256 digest, as a CID (v0 or v1, in any multibase), or as a hex encoded
+ * multihash, which is taken to be the hash of the raw file bytes.
+ *
+ * SHA-256 hashes of raw file bytes normalize to their digest in lowercase hex, the form
+ * files were first keyed by. Anything else normalizes to a CIDv1 in base32.
+ *
+ * Parameters:
+ *   hash: The file hash as given by a user
+ *
+ * Returns:
+ *   The normalized hash
+ *   An error, if the hash cannot be decoded, uses a hash function we do not accept, or
+ *   has the wrong digest length for its hash function
+ */
+func NormalizeFileHash(hash string) (string, error) {
+	if hexFileHashPattern.MatchString(hash) {
+		return strings.ToLower(hash), nil
+	}
+
+	var mh multihash.Multihash
+	codec := uint64(cid.Raw)
+	if c, err := cid.Decode(hash); err == nil {
+		mh = c.Hash()
+		codec = c.Type()
+	} else if mh, err = multihash.FromHexString(hash); err != nil {
+		return "", fmt.Errorf("file hash %q is neither a SHA-256 digest, a CID nor a multihash", hash)
+	}
+
+	decoded, err := multihash.Decode(mh)
+	if err != nil {
+		return "", err
+	}
+	length, ok := fileHashDigestLengths[decoded.Code]
+	if !ok {
+		return "", fmt.Errorf("file hash %q uses unsupported hash function %s", hash, decoded.Name)
+	}
+	if decoded.Length != length {
+		return "", fmt.Errorf("file hash %q has a %d byte digest, %s digests are %d bytes", hash, decoded.Length, decoded.Name, length)
+	}
+
+	if decoded.Code == multihash.SHA2_256 && codec == cid.Raw {
+		return hex.EncodeToString(decoded.Digest), nil
+	}
+	return cid.NewCidV1(codec, mh).String(), nil
+}
+
+/*
+ * Find the CID a file is provided under on the DHT. It is the normalized hash itself if
+ * that is a CID, otherwise the CIDv1 of raw bytes with the hash's SHA-256 digest.
+ *
+ * Parameters:
+ *   hash: The file hash, normalized with NormalizeFileHash if it is not already
+ *
+ * Returns:
+ *   The CID of the file
+ *   An error, if the hash is not valid
+ */
+func FileCID(hash string) (cid.Cid, error) {
+	hash, err := NormalizeFileHash(hash)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if !hexFileHashPattern.MatchString(hash) {
+		return cid.Decode(hash)
+	}
+	digest, err := hex.DecodeString(hash)
+	if err != nil {
+		return cid.Undef, err
+	}
+	mh, err := multihash.Encode(digest, multihash.SHA2_256)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return cid.NewCidV1(cid.Raw, mh), nil
+}
+
+// Matches keywords once lowercased: letters, digits, dashes and underscores.
+var keywordPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,64}$`)
+
+/*
+ * Normalize a search keyword to the form it is indexed under.
+ *
+ * Parameters:
+ *   keyword: The keyword as given by a user
+ *
+ * Returns:
+ *   The keyword, trimmed and lowercased
+ *   An error, if it is empty, longer than 64 bytes or has characters other than letters,
+ *   digits, dashes and underscores
+ */
+func NormalizeKeyword(keyword string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(keyword))
+	if !keywordPattern.MatchString(normalized) || len(normalized) > 64 {
+		return "", fmt.Errorf("invalid keyword %q, expected up to 64 letters, digits, dashes or underscores", keyword)
+	}
+	return normalized, nil
+}
+
+/*
+ * Check for peers who have announced themselves on the DHT.
+ * If the DHT is running in server mode, then we will announce ourselves and check for
+ * others who have announced as well. In an auto mode we announce ourselves whenever
+ * AutoNAT finds us reachable, see FollowReachability.
  *
  * Parameters:
  *   context: The context
@@ -113,9 +349,11 @@ func CheckOrCreatePrivateKey(path string) (crypto.PrivKey, error) {
  *
  */
 func DiscoverPeers(ctx context.Context, h host.Host, kDHT *dht.IpfsDHT, advertise string) {
-	routingDiscovery := drouting.NewRoutingDiscovery(kDHT);
-	if(kDHT.Mode() == dht.ModeServer){
-		dutil.Advertise(ctx, routingDiscovery, advertise);
+	routingDiscovery := drouting.NewRoutingDiscovery(kDHT)
+	if kDHT.Mode() == dht.ModeServer {
+		dutil.Advertise(ctx, routingDiscovery, advertise)
+	} else if kDHT.Mode() == dht.ModeAuto || kDHT.Mode() == dht.ModeAutoServer {
+		go FollowReachability(ctx, h, kDHT, advertise)
 	}
 
 	// Look for others who have announced and attempt to connect to them
@@ -140,22 +378,25 @@ func DiscoverPeers(ctx context.Context, h host.Host, kDHT *dht.IpfsDHT, advertis
 	}
 }
 
-
 /*
- * Searches for file bootstrap.peer and parses it to get multiaddrs of bootstrap peers.
+ * Reads a bootstrap peers file, such as bootstrap.peers, and parses it to get multiaddrs of bootstrap peers.
  * Each line is a mutliaddr.
  *
+ * Parameters:
+ *   path: Path of the file
+ *
  * Returns:
  *   A slice of libp2p multiaddrs
  * Author: Erick
  */
-func ReadBootstrapPeers() []multiaddr.Multiaddr {
+func ReadBootstrapPeers(path string) []multiaddr.Multiaddr {
 	peers := []multiaddr.Multiaddr{}
 
-	file, err := os.Open("bootstrap.peers")
+	file, err := os.Open(path)
 	if err != nil {
 		panic(err)
 	}
+	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	scanner.Split(bufio.ScanLines)
@@ -187,7 +428,7 @@ func ConvertBytesTo64BitInt(value []byte) uint64 {
 	shift := 7
 	for i := 0; i < len(value); i++ {
 		suppliedTime = suppliedTime | (uint64(value[i]) << (shift * 8))
-		shift--;
+		shift--
 	}
 	return suppliedTime
-}
\ No newline at end of file
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+// SHA-256 of "test" as a hex digest, and the same digest in the other forms a file may be given in.
+const (
+	sha256Hex       = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	sha256Multihash = "1220" + sha256Hex
+	sha256RawCID    = "bafkreie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba"
+	sha256RawBase58 = "zb2rhhP1FKrgjtjqJk35nPsRudb2FHC7Myu2pqcjpYckDHTJf"
+	sha256CIDv0     = "QmZ5NmGeStdit7tV6gdak1F8FyZhPsfA843YS9f2ywKH6w"
+	sha256DagPbCID  = "bafybeie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba"
+	// BLAKE3 of "test", as a hex multihash and as a CIDv1 of raw bytes.
+	blake3Multihash = "1e204878ca0425c739fa427f7eda20fe845f6b2e46ba5fe2a14df5b1e32f50603215"
+	blake3RawCID    = "bafkr4icipdfaijohhh5ee7363iqp5bc7nmxenos74kqu35nr4mxvaybscu"
+)
+
+// Every form of the same hash normalizes to one key, and anything else is rejected.
+func TestNormalizeFileHash(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+		want string
+		ok   bool
+	}{
+		{"hex digest", sha256Hex, sha256Hex, true},
+		{"uppercase hex digest", strings.ToUpper(sha256Hex), sha256Hex, true},
+		{"mixed case hex digest", "9F86d081884C7D659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00A08", sha256Hex, true},
+		{"raw CID of a SHA-256 digest", sha256RawCID, sha256Hex, true},
+		{"raw CID in base58", sha256RawBase58, sha256Hex, true},
+		{"hex multihash", sha256Multihash, sha256Hex, true},
+		{"CIDv0", sha256CIDv0, sha256DagPbCID, true},
+		{"dag-pb CIDv1", sha256DagPbCID, sha256DagPbCID, true},
+		{"BLAKE3 multihash", blake3Multihash, blake3RawCID, true},
+		{"BLAKE3 CID", blake3RawCID, blake3RawCID, true},
+		{"empty", "", "", false},
+		{"leading whitespace", " " + sha256Hex, "", false},
+		{"trailing newline", sha256Hex + "\n", "", false},
+		{"short hex digest", sha256Hex[:62], "", false},
+		{"long hex digest", sha256Hex + "00", "", false},
+		{"non-hex digest", "g" + sha256Hex[1:], "", false},
+		{"truncated BLAKE3 digest", "1e104878ca0425c739fa427f7eda20fe845f", "", false},
+		{"unsupported hash function", "d50110098f6bcd4621d373cade4e832627b4f6", "", false},
+		{"garbage", "not-a-hash", "", false},
+	}
+	for _, test := range tests {
+		got, err := NormalizeFileHash(test.hash)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: NormalizeFileHash(%q) error = %v, want ok %v", test.name, test.hash, err, test.ok)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: NormalizeFileHash(%q) = %q, want %q", test.name, test.hash, got, test.want)
+		}
+	}
+}
+
+// A file is provided under the same CID whatever form its hash is given in.
+func TestFileCID(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+		want string
+		ok   bool
+	}{
+		{"hex digest", sha256Hex, sha256RawCID, true},
+		{"mixed case hex digest", "9F86d081884C7D659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00A08", sha256RawCID, true},
+		{"raw CID", sha256RawCID, sha256RawCID, true},
+		{"hex multihash", sha256Multihash, sha256RawCID, true},
+		{"CIDv0", sha256CIDv0, sha256DagPbCID, true},
+		{"BLAKE3 multihash", blake3Multihash, blake3RawCID, true},
+		{"whitespace", sha256Hex + " ", "", false},
+		{"wrong length", sha256Hex[:63], "", false},
+		{"non-hex", strings.Repeat("z", 64), "", false},
+	}
+	for _, test := range tests {
+		got, err := FileCID(test.hash)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: FileCID(%q) error = %v, want ok %v", test.name, test.hash, err, test.ok)
+			continue
+		}
+		if test.ok && got.String() != test.want {
+			t.Errorf("%s: FileCID(%q) = %s, want %s", test.name, test.hash, got, test.want)
+		}
+	}
+}
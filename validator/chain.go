@@ -0,0 +1,210 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"time"
+
+	"orcanet/record"
+)
+
+// A decoded entry of a chain along with the canonical id of the public key that signed it.
+type chainEntry struct {
+	id    []byte
+	entry *record.Entry
+}
+
+/*
+ * Select the best of several values of a chain. The newest record for every public key
+ * across all valid values wins, and of those only the max newest count when the chain is
+ * capped, as they are all Merge keeps. Only counted winners that have not expired are
+ * compared, so a writer that prunes expired records or replaces the oldest records of a
+ * full chain is not outvoted by what it removed.
+ *
+ * A value holding every live winner of the best so far, and more, replaces it. One holding
+ * the same live winners replaces it only if it has fewer records, so a pruned copy wins
+ * over one still carrying dead records, and a value holding exactly the same records does
+ * not. A value holding a live winner the best lacks cannot be ordered against it, however
+ * many winners either holds, and the latest wins: kad-dht passes the stored record last
+ * when a peer puts a value, so such a put keeps what is stored rather than dropping its
+ * winners, and passes the newly received value last during a lookup, so the lookup still
+ * hands it on to be merged.
+ *
+ * Parameters:
+ *   key: The DHT key the values are stored under
+ *   value: The values to compare
+ *   validate: Checks a value for the key
+ *   entryID: Finds the canonical id of the public key an entry belongs to
+ *   max: Most entries the chain may hold, or 0 for no limit
+ *
+ * Returns:
+ *   The index of the best value
+ *   An error, if none of the values are valid
+ */
+func selectChain(key string, value [][]byte, validate func(string, []byte) error, entryID func(*record.Entry) ([]byte, error), max int) (int, error) {
+	candidates, newest := collectChains(key, value, validate, entryID)
+	if len(candidates) == 0 {
+		return 0, errors.New("No valid value to select!")
+	}
+	counted := countedWinners(newest, max)
+
+	bestIndex := -1
+	var bestWinners map[string]bool
+	for i, entries := range candidates {
+		if entries == nil {
+			continue
+		}
+		winners := make(map[string]bool)
+		for _, e := range entries {
+			winner, ok := counted[string(e.id)]
+			if ok && winner.RegisteredAt == e.entry.RegisteredAt && bytes.Equal(winner.Signature, e.entry.Signature) {
+				winners[string(e.id)] = true
+			}
+		}
+
+		better := bestIndex < 0 || !subset(winners, bestWinners)
+		if !better && len(winners) == len(bestWinners) {
+			better = len(entries) < len(candidates[bestIndex])
+		}
+		if better {
+			bestIndex = i
+			bestWinners = winners
+		}
+	}
+	return bestIndex, nil
+}
+
+/*
+ * Find the winning records that count towards selecting a value: the newest record of
+ * every public key, of which only the max newest if max is not 0, that has not expired.
+ *
+ * Returns:
+ *   The counted records, keyed by the canonical id of their public key
+ */
+func countedWinners(newest map[string]chainEntry, max int) map[string]*record.Entry {
+	winners := make([]chainEntry, 0, len(newest))
+	for _, e := range newest {
+		winners = append(winners, e)
+	}
+	if max > 0 && len(winners) > max {
+		sort.Slice(winners, func(i, j int) bool {
+			return newerEntry(winners[i].entry, winners[j].entry)
+		})
+		winners = winners[:max]
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	counted := make(map[string]*record.Entry, len(winners))
+	for _, e := range winners {
+		if !e.entry.Expired(now) {
+			counted[string(e.id)] = e.entry
+		}
+	}
+	return counted
+}
+
+// Whether every key of a is in b.
+func subset(a map[string]bool, b map[string]bool) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * Merge several values of a chain into one holding the newest record for every public key
+ * found in any valid value. Invalid values are skipped.
+ *
+ * Parameters:
+ *   key: The DHT key the values are stored under
+ *   value: The values to merge
+ *   validate: Checks a value for the key
+ *   entryID: Finds the canonical id of the public key an entry belongs to
+ *
+ * Returns:
+ *   The merged value, which is valid if all records it was built from were
+ *   An error, if none of the values are valid
+ */
+func mergeChains(key string, value [][]byte, validate func(string, []byte) error, entryID func(*record.Entry) ([]byte, error)) ([]byte, error) {
+	candidates, newest := collectChains(key, value, validate, entryID)
+	if len(candidates) == 0 {
+		return nil, errors.New("No valid value to merge!")
+	}
+
+	merged := &record.Chain{Entries: make([]record.Entry, 0, len(newest))}
+	seen := make(map[string]bool)
+	for _, entries := range candidates {
+		// keep records in the order they first appear so merging is stable
+		for _, e := range entries {
+			if !seen[string(e.id)] {
+				seen[string(e.id)] = true
+				merged.Entries = append(merged.Entries, *newest[string(e.id)].entry)
+			}
+		}
+	}
+	return record.Encode(merged)
+}
+
+/*
+ * Decode every valid value and find the newest record for each public key.
+ *
+ * Returns:
+ *   The entries of each value, nil for values that are invalid. Empty if none are valid.
+ *   The newest entry for each public key, keyed by the public key bytes
+ */
+func collectChains(key string, value [][]byte, validate func(string, []byte) error, entryID func(*record.Entry) ([]byte, error)) ([][]chainEntry, map[string]chainEntry) {
+	candidates := make([][]chainEntry, len(value))
+	newest := make(map[string]chainEntry)
+	valid := 0
+	for i := range value {
+		if validate(key, value[i]) != nil {
+			continue
+		}
+		chain, err := record.Decode(value[i])
+		if err != nil {
+			continue
+		}
+
+		entries := make([]chainEntry, 0, len(chain.Entries))
+		for j := range chain.Entries {
+			id, err := entryID(&chain.Entries[j])
+			if err != nil {
+				break
+			}
+			entries = append(entries, chainEntry{id: id, entry: &chain.Entries[j]})
+		}
+		if len(entries) != len(chain.Entries) {
+			continue
+		}
+
+		candidates[i] = entries
+		valid++
+		for _, e := range entries {
+			current, ok := newest[string(e.id)]
+			if !ok || newerEntry(e.entry, current.entry) {
+				newest[string(e.id)] = e
+			}
+		}
+	}
+	if valid == 0 {
+		return nil, newest
+	}
+	return candidates, newest
+}
+
+/*
+ * Decide whether entry a supersedes entry b for the same public key. The later
+ * registration wins, ties are broken on the signature bytes so every node agrees.
+ */
+func newerEntry(a *record.Entry, b *record.Entry) bool {
+	if a.RegisteredAt != b.RegisteredAt {
+		return a.RegisteredAt > b.RegisteredAt
+	}
+	return bytes.Compare(a.Signature, b.Signature) > 0
+}
@@ -0,0 +1,157 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "orcanet/market"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// Prefix of the DHT keys keyword index chains are stored under, followed by the keyword.
+const IndexPrefix = "orcanet/index/"
+
+// Validates the keyword index chains used by Search. An index chain uses the same format
+// as a holder chain, but each entry holds the IndexEntry of the files one node registered
+// under the keyword.
+type IndexValidator struct{}
+
+/*
+ * Validates keys and values that are being put under orcanet/index/<keyword>.
+ * Every record must be signed by its publisher, there may be only one record per
+ * publisher and at most record.MaxIndexPublishers records, and each record must list a
+ * sorted set of file hashes.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the normalized keyword
+ *   value: The index chain, must conform to the specification in /validator/README.md
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (v IndexValidator) Validate(key string, value []byte) error {
+	keyword := strings.TrimPrefix(key, IndexPrefix)
+	normalized, err := util.NormalizeKeyword(keyword)
+	if !strings.HasPrefix(key, IndexPrefix) || err != nil || normalized != keyword {
+		return errors.New("Provided key is not an index key for a normalized keyword!")
+	}
+
+	chain, err := record.Decode(value)
+	if err != nil {
+		return err
+	}
+	if len(chain.Entries) > record.MaxIndexPublishers {
+		return errors.New("Index chain lists too many publishers!")
+	}
+
+	publishers := make(map[string]bool)
+	now := uint64(time.Now().UTC().Unix())
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		index := &pb.IndexEntry{}
+		if err := proto.Unmarshal(entry.Message, index); err != nil {
+			return err
+		}
+
+		publicKey, err := util.UnmarshalUserID(index.GetPublisher())
+		if err != nil {
+			return err
+		}
+		id, err := util.MarshalUserID(publicKey)
+		if err != nil {
+			return err
+		}
+		if publishers[string(id)] {
+			return errors.New("Duplicate index record by the same publisher found!")
+		}
+		publishers[string(id)] = true
+
+		hashes := index.GetFileHashes()
+		if len(hashes) > record.MaxIndexedFiles {
+			return errors.New("Index record lists too many files!")
+		}
+		for j, hash := range hashes {
+			if !isFileHash(hash) {
+				return errors.New("Index record lists a value that is not a file hash!")
+			}
+			// sorted without duplicates, so every set has one encoding
+			if j > 0 && hashes[j-1] >= hash {
+				return errors.New("Index record file hashes are not sorted and unique!")
+			}
+		}
+
+		if entry.RegisteredAt > now+uint64(MaxClockSkew.Seconds()) {
+			return errors.New("Index record time cannot be in the future!")
+		}
+		if entry.TTL == 0 || entry.TTL > uint32(record.MaxEntryTTL.Seconds()) {
+			return errors.New("Index record TTL is out of range!")
+		}
+		if entry.Flags != 0 {
+			return errors.New("Index record has flags set!")
+		}
+
+		valid, err := publicKey.Verify(entry.SigningPayloadWithDomain(record.IndexSignatureDomain, keyword), entry.Signature)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("Signature invalid!")
+		}
+	}
+	return nil
+}
+
+/*
+ * Given a list of index chains from the DHT, select index of the best one. Chains are
+ * merged per publisher the same way holder chains are merged per holder, and only the
+ * records Merge keeps count, so a new publisher can join a full chain.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the normalized keyword
+ *   value: The index chains to compare
+ *
+ * Returns:
+ *   The index of the best value
+ *   An error, if none of the values are valid
+ */
+func (v IndexValidator) Select(key string, value [][]byte) (int, error) {
+	return selectChain(key, value, v.Validate, indexPublisherID, record.MaxIndexPublishers)
+}
+
+/*
+ * Merge a list of index chains from the DHT into a single chain holding the newest
+ * record of every publisher, of which only the record.MaxIndexPublishers newest are kept.
+ * Invalid values are skipped.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the normalized keyword
+ *   value: The index chains to merge
+ *
+ * Returns:
+ *   The merged value
+ *   An error, if none of the values are valid
+ */
+func (v IndexValidator) Merge(key string, value [][]byte) ([]byte, error) {
+	merged, err := mergeChains(key, value, v.Validate, indexPublisherID)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := record.Decode(merged)
+	if err != nil {
+		return nil, err
+	}
+	chain.KeepNewest(record.MaxIndexPublishers)
+	return record.Encode(chain)
+}
+
+// The canonical id of the public key that signed an index chain entry.
+func indexPublisherID(entry *record.Entry) ([]byte, error) {
+	index := &pb.IndexEntry{}
+	if err := proto.Unmarshal(entry.Message, index); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(index.GetPublisher())
+}
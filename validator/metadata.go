@@ -0,0 +1,154 @@
+package validator
+
+import (
+	"errors"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "orcanet/market"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+const (
+	// Prefix of the DHT keys metadata chains are stored under, followed by the file hash.
+	MetadataPrefix = "orcanet/meta/"
+	// Longest file name or MIME type a metadata record may hold.
+	MaxMetadataFieldLength = 255
+)
+
+// Validates the metadata chains of files. A metadata chain uses the same format as a
+// holder chain, but each entry holds the FileMetadata a node published for the file.
+type MetadataValidator struct{}
+
+/*
+ * Validates keys and values that are being put under orcanet/meta/<hash>.
+ * Every record must be signed by its publisher, and there may be only one record per
+ * publisher and at most record.MaxMetadataPublishers records.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the hash of the file
+ *   value: The metadata chain, must conform to the specification in /validator/README.md
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (v MetadataValidator) Validate(key string, value []byte) error {
+	hash := strings.TrimPrefix(key, MetadataPrefix)
+	if !strings.HasPrefix(key, MetadataPrefix) || !isFileHash(hash) {
+		return errors.New("Provided key is not a metadata key for a file hash!")
+	}
+
+	chain, err := record.Decode(value)
+	if err != nil {
+		return err
+	}
+	if len(chain.Entries) > record.MaxMetadataPublishers {
+		return errors.New("Metadata chain lists too many publishers!")
+	}
+
+	publishers := make(map[string]bool)
+	now := uint64(time.Now().UTC().Unix())
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		metadata := &pb.FileMetadata{}
+		if err := proto.Unmarshal(entry.Message, metadata); err != nil {
+			return err
+		}
+
+		publicKey, err := util.UnmarshalUserID(metadata.GetPublisher())
+		if err != nil {
+			return err
+		}
+		id, err := util.MarshalUserID(publicKey)
+		if err != nil {
+			return err
+		}
+		if publishers[string(id)] {
+			return errors.New("Duplicate metadata by the same publisher found!")
+		}
+		publishers[string(id)] = true
+
+		if len(metadata.GetName()) > MaxMetadataFieldLength || len(metadata.GetMimeType()) > MaxMetadataFieldLength {
+			return errors.New("Metadata field is too long!")
+		}
+		if metadata.GetMimeType() != "" {
+			if _, _, err := mime.ParseMediaType(metadata.GetMimeType()); err != nil {
+				return err
+			}
+		}
+
+		if entry.RegisteredAt > now+uint64(MaxClockSkew.Seconds()) {
+			return errors.New("Metadata publication time cannot be in the future!")
+		}
+		if entry.TTL == 0 || entry.TTL > uint32(record.MaxMetadataTTL.Seconds()) {
+			return errors.New("Metadata TTL is out of range!")
+		}
+		if entry.Flags != 0 {
+			return errors.New("Metadata has flags set!")
+		}
+
+		valid, err := publicKey.Verify(entry.SigningPayloadWithDomain(record.MetadataSignatureDomain, hash), entry.Signature)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("Signature invalid!")
+		}
+	}
+	return nil
+}
+
+/*
+ * Given a list of metadata chains from the DHT, select index of the best one. Chains are
+ * merged per publisher the same way holder chains are merged per holder, and only the
+ * records Merge keeps count.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the hash of the file
+ *   value: The metadata chains to compare
+ *
+ * Returns:
+ *   The index of the best value
+ *   An error, if none of the values are valid
+ */
+func (v MetadataValidator) Select(key string, value [][]byte) (int, error) {
+	return selectChain(key, value, v.Validate, publisherID, record.MaxMetadataPublishers)
+}
+
+/*
+ * Merge a list of metadata chains from the DHT into a single chain holding the newest
+ * record of every publisher, of which only the record.MaxMetadataPublishers newest are
+ * kept. Invalid values are skipped.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the hash of the file
+ *   value: The metadata chains to merge
+ *
+ * Returns:
+ *   The merged value
+ *   An error, if none of the values are valid
+ */
+func (v MetadataValidator) Merge(key string, value [][]byte) ([]byte, error) {
+	merged, err := mergeChains(key, value, v.Validate, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := record.Decode(merged)
+	if err != nil {
+		return nil, err
+	}
+	chain.KeepNewest(record.MaxMetadataPublishers)
+	return record.Encode(chain)
+}
+
+// The canonical id of the public key that signed a metadata chain entry.
+func publisherID(entry *record.Entry) ([]byte, error) {
+	metadata := &pb.FileMetadata{}
+	if err := proto.Unmarshal(entry.Message, metadata); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(metadata.GetPublisher())
+}
@@ -0,0 +1,132 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	record "github.com/libp2p/go-libp2p-record"
+)
+
+// Picks the best of several values stored under the same key.
+type Selector interface {
+	Select(key string, value [][]byte) (int, error)
+}
+
+// Combines several values stored under the same key into one. Namespaces whose values
+// are chains implement it so conflicting copies are merged instead of one being dropped.
+type Merger interface {
+	Merge(key string, value [][]byte) ([]byte, error)
+}
+
+// How the values of one namespace of the DHT are checked and chosen between.
+type Namespace struct {
+	// Validates values put under the namespace. If it implements Merger, values under
+	// the namespace can be merged.
+	Validator record.Validator
+	// Picks the best value under the namespace, Validator.Select if nil.
+	Selector Selector
+}
+
+/*
+ * A registry of namespaces sharing the DHT, in the style of libp2p's
+ * record.NamespacedValidator. Keys are routed to the namespace registered with the
+ * longest prefix of the key, so OrcaNet's "orcanet/<name>/" prefixes and libp2p's
+ * "/<name>/" prefixes can be registered side by side.
+ */
+type NamespacedValidator struct {
+	namespaces map[string]Namespace
+}
+
+// Create an empty registry. Use NewOrcaNamespaces for the namespaces OrcaNet uses.
+func NewNamespacedValidator() *NamespacedValidator {
+	return &NamespacedValidator{namespaces: make(map[string]Namespace)}
+}
+
+/*
+ * Build the registry of every namespace OrcaNet stores on the DHT: holder chains,
+ * reputation chains, metadata chains, keyword index chains, holder profiles and libp2p
+ * public keys.
+ * Both the market server and the bootstrap server build their DHT from it, so every node
+ * accepts the same records.
+ *
+ * Returns:
+ *   The registry
+ */
+func NewOrcaNamespaces() *NamespacedValidator {
+	v := NewNamespacedValidator()
+	v.Register(MarketPrefix, Namespace{Validator: OrcaValidator{}})
+	v.Register(ReputationPrefix, Namespace{Validator: ReputationValidator{}})
+	v.Register(MetadataPrefix, Namespace{Validator: MetadataValidator{}})
+	v.Register(IndexPrefix, Namespace{Validator: IndexValidator{}})
+	v.Register(ProfilePrefix, Namespace{Validator: ProfileValidator{}})
+	v.Register("/pk/", Namespace{Validator: record.PublicKeyValidator{}})
+	return v
+}
+
+/*
+ * Register a namespace, replacing any namespace registered with the same prefix.
+ *
+ * Parameters:
+ *   prefix: The prefix of every key in the namespace, including its trailing slash
+ *   ns: The validator and selector of the namespace
+ */
+func (v *NamespacedValidator) Register(prefix string, ns Namespace) {
+	v.namespaces[prefix] = ns
+}
+
+/*
+ * Find the namespace a key belongs to.
+ *
+ * Returns:
+ *   The namespace registered with the longest prefix of key
+ *   An error, if no namespace matches
+ */
+func (v *NamespacedValidator) namespace(key string) (Namespace, error) {
+	longest := -1
+	var found Namespace
+	for prefix, ns := range v.namespaces {
+		if len(prefix) > longest && strings.HasPrefix(key, prefix) {
+			longest = len(prefix)
+			found = ns
+		}
+	}
+	if longest < 0 {
+		return Namespace{}, fmt.Errorf("no validator registered for key %q", key)
+	}
+	return found, nil
+}
+
+// Validate a value with the validator of its key's namespace.
+func (v *NamespacedValidator) Validate(key string, value []byte) error {
+	ns, err := v.namespace(key)
+	if err != nil {
+		return err
+	}
+	return ns.Validator.Validate(key, value)
+}
+
+// Select the best value with the selector of its key's namespace.
+func (v *NamespacedValidator) Select(key string, value [][]byte) (int, error) {
+	ns, err := v.namespace(key)
+	if err != nil {
+		return 0, err
+	}
+	if ns.Selector != nil {
+		return ns.Selector.Select(key, value)
+	}
+	return ns.Validator.Select(key, value)
+}
+
+// Merge values with the validator of their key's namespace, if it can merge.
+func (v *NamespacedValidator) Merge(key string, value [][]byte) ([]byte, error) {
+	ns, err := v.namespace(key)
+	if err != nil {
+		return nil, err
+	}
+	merger, ok := ns.Validator.(Merger)
+	if !ok {
+		return nil, errors.New("values of this namespace cannot be merged")
+	}
+	return merger.Merge(key, value)
+}
@@ -0,0 +1,117 @@
+package validator
+
+import "testing"
+
+// Records which namespace a key was routed to, and selects the given index.
+type routeRecorder struct {
+	name   string
+	routed *string
+	index  int
+}
+
+func (r routeRecorder) Validate(key string, value []byte) error {
+	*r.routed = r.name
+	return nil
+}
+
+func (r routeRecorder) Select(key string, value [][]byte) (int, error) {
+	*r.routed = r.name
+	return r.index, nil
+}
+
+func (r routeRecorder) Merge(key string, value [][]byte) ([]byte, error) {
+	*r.routed = r.name
+	return []byte(r.name), nil
+}
+
+// Keys are routed to the namespace with the longest prefix of the key, and keys outside
+// every namespace are rejected.
+func TestNamespaceRouting(t *testing.T) {
+	hash := fuzzKey[len(MarketPrefix):]
+	var routed string
+	v := NewNamespacedValidator()
+	v.Register(MarketPrefix, Namespace{Validator: routeRecorder{"chain", &routed, 0}})
+	v.Register(MarketPrefix+hash+"/", Namespace{Validator: routeRecorder{"shards", &routed, 1}})
+	v.Register("/pk/", Namespace{
+		Validator: routeRecorder{"keys", &routed, 0},
+		Selector:  routeRecorder{"key selector", &routed, 2},
+	})
+
+	tests := []struct {
+		key string
+		// The namespace Validate routes to, empty if the key is rejected
+		validate string
+		// The namespace Select routes to and the index it returns
+		selector string
+		index    int
+	}{
+		{MarketPrefix + hash, "chain", "chain", 0},
+		{MarketPrefix + hash + "/3", "shards", "shards", 1},
+		{MarketPrefix + hash + "/", "shards", "shards", 1},
+		{MarketPrefix + "other", "chain", "chain", 0},
+		{"/pk/peer", "keys", "key selector", 2},
+		{"orcanet/unknown/" + hash, "", "", 0},
+		{"orcanet/market", "", "", 0},
+		{"/pk", "", "", 0},
+		{"", "", "", 0},
+	}
+	for _, test := range tests {
+		routed = ""
+		err := v.Validate(test.key, nil)
+		if (err == nil) != (test.validate != "") || routed != test.validate {
+			t.Errorf("Validate(%q) routed to %q (error %v), want %q", test.key, routed, err, test.validate)
+		}
+
+		routed = ""
+		index, err := v.Select(test.key, [][]byte{nil, nil, nil})
+		if (err == nil) != (test.selector != "") || routed != test.selector || index != test.index {
+			t.Errorf("Select(%q) routed to %q and returned %d (error %v), want %q and %d", test.key, routed, index, err, test.selector, test.index)
+		}
+	}
+}
+
+// Only namespaces whose validator is a Merger can merge.
+func TestNamespaceMerge(t *testing.T) {
+	var routed string
+	v := NewNamespacedValidator()
+	v.Register(MarketPrefix, Namespace{Validator: routeRecorder{"chain", &routed, 0}})
+	v.Register("/pk/", Namespace{Validator: NewOrcaNamespaces().namespaces["/pk/"].Validator})
+
+	if merged, err := v.Merge(fuzzKey, nil); err != nil || string(merged) != "chain" {
+		t.Errorf("Merge(%q) = %q, %v, want the chain namespace's merge", fuzzKey, merged, err)
+	}
+	if _, err := v.Merge("/pk/peer", nil); err == nil {
+		t.Error("Merge of a namespace that cannot merge succeeded")
+	}
+	if _, err := v.Merge("orcanet/unknown/key", nil); err == nil {
+		t.Error("Merge of a key outside every namespace succeeded")
+	}
+}
+
+// Every OrcaNet namespace is routed to its own validator.
+func TestOrcaNamespaces(t *testing.T) {
+	v := NewOrcaNamespaces()
+	tests := []struct {
+		key  string
+		want any
+	}{
+		{fuzzKey, OrcaValidator{}},
+		{fuzzKey + "/3", OrcaValidator{}},
+		{ReputationPrefix + "peer", ReputationValidator{}},
+		{MetadataPrefix + "hash", MetadataValidator{}},
+		{IndexPrefix + "music", IndexValidator{}},
+		{ProfilePrefix + "peer/hash", ProfileValidator{}},
+	}
+	for _, test := range tests {
+		ns, err := v.namespace(test.key)
+		if err != nil || ns.Validator != test.want {
+			t.Errorf("%q is validated by %T (error %v), want %T", test.key, ns.Validator, err, test.want)
+		}
+	}
+	if err := v.Validate("orcanet/unknown/key", []byte("value")); err == nil {
+		t.Error("Validate accepted a key outside every namespace")
+	}
+	if _, err := v.Select("orcanet/unknown/key", [][]byte{[]byte("value")}); err == nil {
+		t.Error("Select accepted a key outside every namespace")
+	}
+}
@@ -0,0 +1,118 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	pb "orcanet/market"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// Prefix of the DHT keys holder profiles are stored under, followed by the peer ID of
+// the holder, a slash and the hash of the file.
+const ProfilePrefix = "orcanet/profile/"
+
+// Validates the profiles of holders that list files with provider records. A profile
+// uses the same format as a holder chain, but holds a single entry: the User the holder
+// lists one file with, signed by the key of the peer it is stored under.
+type ProfileValidator struct{}
+
+/*
+ * Validates keys and values that are being put under orcanet/profile/<peer>/<hash>.
+ * The profile must hold exactly one record, signed by the peer's own key.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the peer ID of the holder and the file hash
+ *   value: The profile, must conform to the specification in /validator/README.md
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (v ProfileValidator) Validate(key string, value []byte) error {
+	subject := strings.TrimPrefix(key, ProfilePrefix)
+	peerName, hash, _ := strings.Cut(subject, "/")
+	holder, err := peer.Decode(peerName)
+	if !strings.HasPrefix(key, ProfilePrefix) || err != nil || holder.String() != peerName || !isFileHash(hash) {
+		return errors.New("Provided key is not a profile key for a peer ID and file hash!")
+	}
+
+	chain, err := record.Decode(value)
+	if err != nil {
+		return err
+	}
+	if len(chain.Entries) != 1 {
+		return errors.New("A profile must hold exactly one record!")
+	}
+
+	entry := &chain.Entries[0]
+	user := &pb.User{}
+	if err := proto.Unmarshal(entry.Message, user); err != nil {
+		return err
+	}
+	publicKey, err := util.UnmarshalUserID(user.GetId())
+	if err != nil {
+		return err
+	}
+	signer, err := peer.IDFromPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	if signer != holder {
+		return errors.New("A profile must be signed by the peer it is stored under!")
+	}
+
+	now := uint64(time.Now().UTC().Unix())
+	if entry.RegisteredAt > now+uint64(MaxClockSkew.Seconds()) {
+		return errors.New("Profile registration time cannot be in the future!")
+	}
+	if entry.TTL == 0 || entry.TTL > uint32(record.MaxEntryTTL.Seconds()) {
+		return errors.New("Profile TTL is out of range!")
+	}
+	if entry.Flags != 0 {
+		return errors.New("Profile has flags set!")
+	}
+
+	valid, err := publicKey.Verify(entry.SigningPayloadWithDomain(record.ProfileSignatureDomain, subject), entry.Signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("Signature invalid!")
+	}
+	return nil
+}
+
+/*
+ * Given a list of profiles from the DHT, select index of the newest one.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the peer ID of the holder and the file hash
+ *   value: The profiles to compare
+ *
+ * Returns:
+ *   The index of the best value
+ *   An error, if none of the values are valid
+ */
+func (v ProfileValidator) Select(key string, value [][]byte) (int, error) {
+	return selectChain(key, value, v.Validate, holderID, 0)
+}
+
+/*
+ * Merge a list of profiles from the DHT. Every profile under a key is signed by the same
+ * peer, so this is the newest profile.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the peer ID of the holder and the file hash
+ *   value: The profiles to merge
+ *
+ * Returns:
+ *   The merged value
+ *   An error, if none of the values are valid
+ */
+func (v ProfileValidator) Merge(key string, value [][]byte) ([]byte, error) {
+	return mergeChains(key, value, v.Validate, holderID)
+}
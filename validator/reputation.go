@@ -0,0 +1,155 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	pb "orcanet/market"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+// Prefix of the DHT keys reputation chains are stored under, followed by the peer ID of
+// the producer being rated.
+const ReputationPrefix = "orcanet/reputation/"
+
+// Validates the reputation chains of producers. A reputation chain uses the same format
+// as a holder chain, but each entry holds a Rating signed by the consumer that made it.
+type ReputationValidator struct{}
+
+/*
+ * Validates keys and values that are being put under orcanet/reputation/<peer>.
+ * Every rating must be signed by its rater, there may be only one rating per rater and
+ * at most record.MaxRaters ratings, and a producer may not rate itself.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the peer ID of the rated producer
+ *   value: The reputation chain, must conform to the specification in /validator/README.md
+ *
+ * Returns:
+ *   An error, if any
+ */
+func (v ReputationValidator) Validate(key string, value []byte) error {
+	subject := strings.TrimPrefix(key, ReputationPrefix)
+	producer, err := peer.Decode(subject)
+	if !strings.HasPrefix(key, ReputationPrefix) || err != nil || producer.String() != subject {
+		return errors.New("Provided key is not a reputation key for a peer ID!")
+	}
+
+	chain, err := record.Decode(value)
+	if err != nil {
+		return err
+	}
+	if len(chain.Entries) > record.MaxRaters {
+		return errors.New("Reputation chain holds too many ratings!")
+	}
+
+	raters := make(map[string]bool)
+	now := uint64(time.Now().UTC().Unix())
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
+		rating := &pb.Rating{}
+		if err := proto.Unmarshal(entry.Message, rating); err != nil {
+			return err
+		}
+
+		publicKey, err := util.UnmarshalUserID(rating.GetRater())
+		if err != nil {
+			return err
+		}
+		id, err := util.MarshalUserID(publicKey)
+		if err != nil {
+			return err
+		}
+		if raters[string(id)] {
+			return errors.New("Duplicate rating by the same rater found!")
+		}
+		raters[string(id)] = true
+
+		rater, err := peer.IDFromPublicKey(publicKey)
+		if err != nil {
+			return err
+		}
+		if rater == producer {
+			return errors.New("A producer cannot rate itself!")
+		}
+		if rating.GetScore() < record.MinRatingScore || rating.GetScore() > record.MaxRatingScore {
+			return errors.New("Rating score is out of range!")
+		}
+
+		if entry.RegisteredAt > now+uint64(MaxClockSkew.Seconds()) {
+			return errors.New("Rating time cannot be in the future!")
+		}
+		if entry.TTL == 0 || entry.TTL > uint32(record.MaxRatingTTL.Seconds()) {
+			return errors.New("Rating TTL is out of range!")
+		}
+		if entry.Flags != 0 {
+			return errors.New("Rating has flags set!")
+		}
+
+		valid, err := publicKey.Verify(entry.SigningPayloadWithDomain(record.ReputationSignatureDomain, subject), entry.Signature)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return errors.New("Signature invalid!")
+		}
+	}
+	return nil
+}
+
+/*
+ * Given a list of reputation chains from the DHT, select index of the best one. Chains
+ * are merged per rater the same way holder chains are merged per holder, and only the
+ * ratings Merge keeps count, so a chain that took a new rating in place of the oldest
+ * wins over the full chain it was built from.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the peer ID of the rated producer
+ *   value: The reputation chains to compare
+ *
+ * Returns:
+ *   The index of the best value
+ *   An error, if none of the values are valid
+ */
+func (v ReputationValidator) Select(key string, value [][]byte) (int, error) {
+	return selectChain(key, value, v.Validate, raterID, record.MaxRaters)
+}
+
+/*
+ * Merge a list of reputation chains from the DHT into a single chain holding the newest
+ * rating by every rater, of which only the record.MaxRaters newest are kept. Invalid
+ * values are skipped.
+ *
+ * Parameters:
+ *   key: The DHT key, holding the peer ID of the rated producer
+ *   value: The reputation chains to merge
+ *
+ * Returns:
+ *   The merged value
+ *   An error, if none of the values are valid
+ */
+func (v ReputationValidator) Merge(key string, value [][]byte) ([]byte, error) {
+	merged, err := mergeChains(key, value, v.Validate, raterID)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := record.Decode(merged)
+	if err != nil {
+		return nil, err
+	}
+	chain.KeepNewest(record.MaxRaters)
+	return record.Encode(chain)
+}
+
+// The canonical id of the public key that signed a reputation chain entry.
+func raterID(entry *record.Entry) ([]byte, error) {
+	rating := &pb.Rating{}
+	if err := proto.Unmarshal(entry.Message, rating); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(rating.GetRater())
+}
@@ -1,99 +1,218 @@
 package validator
 
 import (
-	"regexp"
-	"strings"
 	"errors"
-	"time"
 	"github.com/golang/protobuf/proto"
-	crypto "github.com/libp2p/go-libp2p/core/crypto"
 	pb "orcanet/market"
+	"orcanet/record"
 	"orcanet/util"
-	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	// How far ahead of our clock a record's registration time may be.
+	MaxClockSkew = 5 * time.Minute
+	// Prefix of the DHT keys holder chains are stored under, followed by the file hash and,
+	// for a shard of the chain, a slash and the shard number.
+	MarketPrefix = "orcanet/market/"
+)
+
+// Validates the holder chains of files.
 type OrcaValidator struct{}
 
 /*
- * Given a list of values from the DHT, select index of the best one. This is determined by
- * checking which value is the longest and is valid according to the OrcaValidator. 
- * 
+ * Given a list of values from the DHT, select index of the best one. Values are merged
+ * like a CRDT: the newest record for every public key across all valid values wins, and
+ * the best value is the one holding the most of those winning records, of which only the
+ * record.MaxShardEntries newest count in a shard. Invalid values are never selected.
+ *
  * Parameters:
  *   key: SHA256 Hash String of file being registered
- *   value: A slice of byte slices that represent the values to be compared. 
+ *   value: A slice of byte slices that represent the values to be compared.
  *
  * Returns:
  *   The index of the best value
- *   An error, if any
+ *   An error, if none of the values are valid
  * Author: Austin
  */
-func (v OrcaValidator) Select(key string, value [][]byte) (int, error){
-	max := len(value[0])
-	maxIndex := 0
-	latestTime := util.ConvertBytesTo64BitInt(value[0][(len(value[0]) - 8):]);
-	for i := 1; i < len(value); i++ {
-		suppliedTime := util.ConvertBytesTo64BitInt(value[i][(len(value[i]) - 8):])
-		fmt.Println(suppliedTime)
-		if(len(value[i]) >= max){
-			if(suppliedTime >= latestTime){
-				max = len(value[i]);
-				latestTime = suppliedTime;
-				maxIndex = i;
-			}
-		}
+func (v OrcaValidator) Select(key string, value [][]byte) (int, error) {
+	return selectChain(key, value, v.Validate, holderID, entryCap(key))
+}
+
+/*
+ * Merge a list of values from the DHT into a single chain holding the newest record for
+ * every public key found in any valid value, of which only the record.MaxShardEntries
+ * newest are kept in a shard. Invalid values are skipped.
+ *
+ * Parameters:
+ *   key: SHA256 Hash String of file being registered
+ *   value: A slice of byte slices that represent the values to be merged.
+ *
+ * Returns:
+ *   The merged value, which is valid if all records it was built from were
+ *   An error, if none of the values are valid
+ */
+func (v OrcaValidator) Merge(key string, value [][]byte) ([]byte, error) {
+	merged, err := mergeChains(key, value, v.Validate, holderID)
+	max := entryCap(key)
+	if err != nil || max == 0 {
+		return merged, err
+	}
+	chain, err := record.Decode(merged)
+	if err != nil {
+		return nil, err
+	}
+	chain.KeepNewest(max)
+	return record.Encode(chain)
+}
+
+// Most entries the holder chain under a key keeps when merged: record.MaxShardEntries for
+// a shard, or 0 for no limit. The chain under orcanet/market/<hash> is not capped by
+// merging, its writers move new records to the shards once it is full.
+func entryCap(key string) int {
+	if _, shard, err := parseMarketKey(key); err == nil && shard >= 0 {
+		return record.MaxShardEntries
 	}
-	return maxIndex, nil;
+	return 0
+}
+
+/*
+ * Whether a DHT key, without its prefix, is the hash of a file in the form
+ * util.NormalizeFileHash gives it, so each file has a single key.
+ */
+func isFileHash(hash string) bool {
+	normalized, err := util.NormalizeFileHash(hash)
+	return err == nil && normalized == hash
+}
+
+/*
+ * Split a holder chain key into the file hash and the shard it names.
+ *
+ * Parameters:
+ *   key: The DHT key, orcanet/market/<hash> or orcanet/market/<hash>/<n>
+ *
+ * Returns:
+ *   The file hash
+ *   The shard number, -1 for the unsharded chain older nodes write
+ *   An error, if the key is not a holder chain key
+ */
+func parseMarketKey(key string) (string, int, error) {
+	invalid := errors.New("Provided key is not in the form of a file hash!")
+	if !strings.HasPrefix(key, MarketPrefix) {
+		return "", 0, invalid
+	}
+	hash, shardName, sharded := strings.Cut(strings.TrimPrefix(key, MarketPrefix), "/")
+	if !isFileHash(hash) {
+		return "", 0, invalid
+	}
+	if !sharded {
+		return hash, -1, nil
+	}
+	shard, err := strconv.Atoi(shardName)
+	if err != nil || shard < 0 || shard >= record.ShardCount || strconv.Itoa(shard) != shardName {
+		return "", 0, errors.New("Provided key does not name a valid shard!")
+	}
+	return hash, shard, nil
+}
+
+// The canonical id of the public key that signed a holder chain entry.
+func holderID(entry *record.Entry) ([]byte, error) {
+	user := &pb.User{}
+	if err := proto.Unmarshal(entry.Message, user); err != nil {
+		return nil, err
+	}
+	return util.CanonicalUserID(user.GetId())
 }
 
 /*
  * Validates keys and values that are being put into the OrcaNet market DHT.
- * Keys must conform to a SHA256 hash, Values must conform the specification in /server/README.md
- * 
+ * Keys must conform to a SHA256 hash or a normalized multihash with the right digest length
+ * for its hash function, optionally followed by a shard number, in which case every record
+ * must belong in that shard. A chain may hold at most record.ShardThreshold live records
+ * and a shard at most record.MaxShardEntries records, so neither grows past what a DHT
+ * message can carry. Values must conform the specification in /validator/README.md
+ * Every record must be signed by its public key, which may be an RSA, Ed25519 or secp256k1
+ * key. Records that have outlived their TTL are still accepted, so one holder going away
+ * does not invalidate the chain for every other holder. They are ignored everywhere else:
+ * Select does not count them, readers skip them and writers prune them.
+ *
  * Parameters:
  *   key: SHA256 Hash String of file being registered
- *   value: The value to be put into the DHT, must conform to specification in /server/README.md
+ *   value: The value to be put into the DHT, must conform to specification in /validator/README.md
  *
  * Returns:
  *   An error, if any
  * Author: Austin
  */
-func (v OrcaValidator) Validate(key string, value []byte) error{
-	// verify key is a sha256 hash
-	hexPattern := "^[a-fA-F0-9]{64}$"
-	regex := regexp.MustCompile(hexPattern)
-	if !regex.MatchString(strings.Replace(key, "orcanet/market/", "", -1)) {
-		return errors.New("Provided key is not in the form of a SHA-256 digest!")
+func (v OrcaValidator) Validate(key string, value []byte) error {
+	// verify key is a sha256 hash or a normalized multihash, optionally followed by a shard
+	hash, shard, err := parseMarketKey(key)
+	if err != nil {
+		return err
+	}
+
+	chain, err := record.Decode(value)
+	if err != nil {
+		return err
 	}
+	if shard >= 0 && len(chain.Entries) > record.MaxShardEntries {
+		return errors.New("Shard holds too many records!")
+	}
+
+	pubKeySet := make(map[string]bool)
+	live := 0
 
-	pubKeySet := make(map[string] bool)
+	currentTime := time.Now().UTC()
+	unixTimestamp := currentTime.Unix()
+	unixTimestampInt64 := uint64(unixTimestamp)
 
-	for i := 0; i < len(value) - 8; i++ {
-		messageLength := uint16(value[i + 1]) << 8 | uint16(value[i])
-		digitalSignatureLength := uint16(value[i + 3]) << 8 | uint16(value[i + 2])
-		contentLength := messageLength + digitalSignatureLength
+	for i := range chain.Entries {
+		entry := &chain.Entries[i]
 		user := &pb.User{}
 
-		err := proto.Unmarshal(value[i + 4:i + 4 + int(messageLength)], user) 
+		err := proto.Unmarshal(entry.Message, user)
 		if err != nil {
 			return err
 		}
 
-		if pubKeySet[string(user.GetId())] == true {
+		publicKey, err := util.UnmarshalUserID(user.GetId())
+		if err != nil {
+			return err
+		}
+
+		//the same key may be encoded as a legacy RSA id or a libp2p marshalled id
+		id, err := util.MarshalUserID(publicKey)
+		if err != nil {
+			return err
+		}
+		if pubKeySet[string(id)] == true {
 			return errors.New("Duplicate record for the same public key found!")
 		} else {
-			pubKeySet[string(user.GetId())] = true
+			pubKeySet[string(id)] = true
+		}
+		if shard >= 0 && record.Shard(id) != shard {
+			return errors.New("Record does not belong in this shard!")
 		}
 
-		userMessageBytes := value[i + 4:i + 4 + int(messageLength)]
-
-		publicKey, err := crypto.UnmarshalRsaPublicKey(user.GetId())
-		if err != nil{
-			return err
+		if entry.RegisteredAt > unixTimestampInt64+uint64(MaxClockSkew.Seconds()) {
+			return errors.New("Record registration time cannot be in the future!")
 		}
+		if entry.TTL == 0 || entry.TTL > uint32(record.MaxEntryTTL.Seconds()) {
+			return errors.New("Record TTL is out of range!")
+		}
+		if entry.Flags&^record.FlagWithdrawn != 0 {
+			return errors.New("Record has unknown flags set!")
+		}
+		if !entry.Expired(unixTimestampInt64) {
+			live++
+		}
+
+		//registration time, ttl, flags and user message are covered by the signature, bound to the
+		//key the record is stored under so it cannot be copied into another file's chain
+		valid, err := publicKey.Verify(entry.SigningPayload(hash), entry.Signature) //this function will automatically compute hash of data to compare signauture
 
-		signatureBytes := value[i + 4 + int(messageLength):i + 4 + int(contentLength)]
-		valid, err := publicKey.Verify(userMessageBytes, signatureBytes) //this function will automatically compute hash of data to compare signauture
-		
 		if err != nil {
 			return err
 		}
@@ -101,17 +220,11 @@ func (v OrcaValidator) Validate(key string, value []byte) error{
 		if !valid {
 			return errors.New("Signature invalid!")
 		}
-
-		i = i + 4 + int(contentLength) - 1
 	}
 
-    currentTime := time.Now().UTC()
-    unixTimestamp := currentTime.Unix()
-    unixTimestampInt64 := uint64(unixTimestamp)
-
-	suppliedTime := util.ConvertBytesTo64BitInt(value[len(value) - 8:])
-	if(suppliedTime > unixTimestampInt64){
-		return errors.New("Supplied time cannot be less than current time")
+	//a full chain sends new records to its shards, so it never holds more live ones
+	if shard < 0 && live > record.ShardThreshold {
+		return errors.New("Chain holds too many live records!")
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,621 @@
+package validator
+
+import (
+	"crypto/rand"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	pb "orcanet/market"
+	"orcanet/record"
+	"orcanet/util"
+)
+
+const fuzzKey = "orcanet/market/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+// Validate, Select and Merge see values straight from remote peers and must never panic.
+func FuzzValidate(f *testing.F) {
+	rsaKey, _, err := crypto.GenerateRSAKeyPair(2048, rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	legacyID, err := rsaKey.GetPublic().Raw()
+	if err != nil {
+		f.Fatal(err)
+	}
+	edKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		f.Fatal(err)
+	}
+	edID, err := util.MarshalUserID(edKey.GetPublic())
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	entries := make([]record.Entry, 0)
+	for _, signer := range []struct {
+		key crypto.PrivKey
+		id  []byte
+	}{{rsaKey, legacyID}, {edKey, edID}} {
+		message, err := proto.Marshal(&pb.User{Id: signer.id, Name: "fuzz", Ip: "127.0.0.1", Port: 4000, Price: 1})
+		if err != nil {
+			f.Fatal(err)
+		}
+		entry := record.Entry{RegisteredAt: uint64(time.Now().Unix()), TTL: 3600, Message: message}
+		entry.Signature, err = signer.key.Sign(entry.SigningPayload(fuzzKey[len("orcanet/market/"):]))
+		if err != nil {
+			f.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	valid, err := record.Encode(&record.Chain{Entries: entries})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(valid)
+	f.Add(valid[:len(valid)/2])
+	f.Add([]byte{})
+	f.Add([]byte(record.Magic))
+
+	v := OrcaValidator{}
+	f.Fuzz(func(t *testing.T, value []byte) {
+		v.Validate(fuzzKey, value)
+		v.Select(fuzzKey, [][]byte{valid, value})
+		v.Merge(fuzzKey, [][]byte{value, valid})
+	})
+}
+
+// Holder chain keys name a file hash and, for a shard of a full chain, a shard number.
+func TestParseMarketKey(t *testing.T) {
+	hash := fuzzKey[len(MarketPrefix):]
+	tests := []struct {
+		key   string
+		hash  string
+		shard int
+		ok    bool
+	}{
+		{fuzzKey, hash, -1, true},
+		{fuzzKey + "/0", hash, 0, true},
+		{fuzzKey + "/15", hash, 15, true},
+		{fuzzKey + "/16", "", 0, false},
+		{fuzzKey + "/-1", "", 0, false},
+		{fuzzKey + "/01", "", 0, false},
+		{fuzzKey + "/", "", 0, false},
+		{fuzzKey + "/a", "", 0, false},
+		{fuzzKey + "/1/2", "", 0, false},
+		{"orcanet/meta/" + hash, "", 0, false},
+		{MarketPrefix + "not-a-hash", "", 0, false},
+		{MarketPrefix + "not-a-hash/0", "", 0, false},
+		//only the form util.NormalizeFileHash gives a hash is a key
+		{MarketPrefix + strings.ToUpper(hash), "", 0, false},
+		{MarketPrefix + "9F86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08/0", "", 0, false},
+		{MarketPrefix + "bafkreie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba", "", 0, false},
+		{MarketPrefix + "QmZ5NmGeStdit7tV6gdak1F8FyZhPsfA843YS9f2ywKH6w", "", 0, false},
+		{MarketPrefix + "bafybeie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba", "bafybeie7q3iidccmpvszul7kudcvvuavuo7u6gzlbobczuk5nqk3b4akba", -1, true},
+	}
+	for _, test := range tests {
+		gotHash, gotShard, err := parseMarketKey(test.key)
+		if (err == nil) != test.ok {
+			t.Errorf("parseMarketKey(%q) error = %v, want ok %v", test.key, err, test.ok)
+			continue
+		}
+		if test.ok && (gotHash != test.hash || gotShard != test.shard) {
+			t.Errorf("parseMarketKey(%q) = %q, %d, want %q, %d", test.key, gotHash, gotShard, test.hash, test.shard)
+		}
+	}
+}
+
+// A holder chain entry for fuzzKey, signed by key.
+func signedEntry(t *testing.T, key crypto.PrivKey, registeredAt uint64, price int64, flags byte) record.Entry {
+	t.Helper()
+	id, err := util.MarshalUserID(key.GetPublic())
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := proto.Marshal(&pb.User{Id: id, Name: "test", Ip: "203.0.113.7", Port: 4000, Price: price})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := record.Entry{RegisteredAt: registeredAt, TTL: 3600, Flags: flags, Message: message}
+	entry.Signature, err = key.Sign(entry.SigningPayload(fuzzKey[len(MarketPrefix):]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+// Encode a chain of entries.
+func encodeChain(t *testing.T, entries ...record.Entry) []byte {
+	t.Helper()
+	value, err := record.Encode(&record.Chain{Entries: entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// The signatures of the entries of a chain, which identify them, sorted.
+func chainSignatures(t *testing.T, value []byte) []string {
+	t.Helper()
+	chain, err := record.Decode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatures := make([]string, 0, len(chain.Entries))
+	for _, entry := range chain.Entries {
+		signatures = append(signatures, string(entry.Signature))
+	}
+	slices.Sort(signatures)
+	return signatures
+}
+
+// Copies of a chain merge into the newest entry of every key whatever order they are
+// merged in, and Select picks the copy holding the most of those entries.
+func TestMergeConvergence(t *testing.T) {
+	keys := make([]crypto.PrivKey, 3)
+	for i := range keys {
+		var err error
+		keys[i], _, err = crypto.GenerateEd25519Key(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := uint64(time.Now().Unix())
+	a, b, c := keys[0], keys[1], keys[2]
+
+	older := signedEntry(t, a, now-100, 10, 0)
+	newer := signedEntry(t, a, now-50, 20, 0)
+	withdrawn := signedEntry(t, a, now-10, 0, record.FlagWithdrawn)
+	other := signedEntry(t, b, now-100, 10, 0)
+	third := signedEntry(t, c, now-100, 10, 0)
+	//two entries of one key registered at the same time, told apart by their signatures
+	tieLow, tieHigh := signedEntry(t, b, now-20, 1, 0), signedEntry(t, b, now-20, 2, 0)
+	if string(tieLow.Signature) > string(tieHigh.Signature) {
+		tieLow, tieHigh = tieHigh, tieLow
+	}
+	expired := signedEntry(t, c, now-7200, 10, 0)
+	forged := signedEntry(t, a, now, 99, 0)
+	forged.Signature[0] ^= 0xff
+
+	tests := []struct {
+		name   string
+		values [][]byte
+		want   []record.Entry
+		best   int
+	}{
+		{"newest wins", [][]byte{encodeChain(t, older, other), encodeChain(t, newer)}, []record.Entry{newer, other}, 1},
+		{"newest wins in either order", [][]byte{encodeChain(t, newer), encodeChain(t, older, other)}, []record.Entry{newer, other}, 1},
+		{"tie goes to the greater signature", [][]byte{encodeChain(t, tieLow), encodeChain(t, tieHigh)}, []record.Entry{tieHigh}, 1},
+		{"tombstone shadows an older entry", [][]byte{encodeChain(t, newer, other), encodeChain(t, withdrawn)}, []record.Entry{withdrawn, other}, 1},
+		{"invalid candidate skipped", [][]byte{encodeChain(t, forged, third), encodeChain(t, older)}, []record.Entry{older}, 1},
+		{"malformed candidate skipped", [][]byte{[]byte("ORCA"), encodeChain(t, older, third)}, []record.Entry{older, third}, 1},
+		{"best holds the most winners", [][]byte{encodeChain(t, older), encodeChain(t, newer), encodeChain(t, older, other, third)}, []record.Entry{newer, other, third}, 2},
+		{"expired entry kept but not counted", [][]byte{encodeChain(t, expired, other), encodeChain(t, newer)}, []record.Entry{expired, other, newer}, 1},
+	}
+	v := OrcaValidator{}
+	for _, test := range tests {
+		merged, err := v.Merge(fuzzKey, test.values)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := v.Validate(fuzzKey, merged); err != nil {
+			t.Errorf("%s: merged chain is invalid: %v", test.name, err)
+		}
+		want := chainSignatures(t, encodeChain(t, test.want...))
+		if got := chainSignatures(t, merged); !slices.Equal(got, want) {
+			t.Errorf("%s: merged chain holds %d entries that differ from the %d expected", test.name, len(got), len(want))
+		}
+
+		//merging in reverse order, or merging the result again, converges on the same entries
+		reversed := slices.Clone(test.values)
+		slices.Reverse(reversed)
+		again, err := v.Merge(fuzzKey, append(reversed, merged))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !slices.Equal(chainSignatures(t, again), want) {
+			t.Errorf("%s: merging again in reverse order did not converge", test.name)
+		}
+
+		best, err := v.Select(fuzzKey, test.values)
+		if err != nil || best != test.best {
+			t.Errorf("%s: Select = %d, %v, want %d", test.name, best, err, test.best)
+		}
+	}
+
+	if _, err := v.Merge(fuzzKey, [][]byte{encodeChain(t, forged)}); err == nil {
+		t.Error("Merge accepted only invalid values")
+	}
+	if _, err := v.Select(fuzzKey, [][]byte{encodeChain(t, forged), []byte{}}); err == nil {
+		t.Error("Select accepted only invalid values")
+	}
+}
+
+// A writer prunes expired records and replaces its own record before putting a chain, so
+// what it puts must win over the stored chain it was built from, as a peer deciding on a
+// put (handlePutValue) sees them, while a put that would drop a live record loses.
+func TestSelectPrunedChain(t *testing.T) {
+	keys := make([]crypto.PrivKey, 4)
+	for i := range keys {
+		var err error
+		keys[i], _, err = crypto.GenerateEd25519Key(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := uint64(time.Now().Unix())
+	a, b, c, d := keys[0], keys[1], keys[2], keys[3]
+	expiredA, expiredB, expiredC := signedEntry(t, a, now-7200, 10, 0), signedEntry(t, b, now-7200, 10, 0), signedEntry(t, c, now-7200, 10, 0)
+	liveA, liveB, liveD := signedEntry(t, a, now-100, 10, 0), signedEntry(t, b, now-100, 10, 0), signedEntry(t, d, now-100, 10, 0)
+	withdrawnA := signedEntry(t, a, now, 0, record.FlagWithdrawn)
+
+	tests := []struct {
+		name     string
+		incoming []byte
+		stored   []byte
+		want     int
+	}{
+		{"expired record pruned for a new one", encodeChain(t, liveB), encodeChain(t, expiredA), 0},
+		{"many expired records pruned for one", encodeChain(t, liveD), encodeChain(t, expiredA, expiredB, expiredC), 0},
+		{"expired record pruned alone", encodeChain(t), encodeChain(t, expiredA), 0},
+		{"expired record pruned beside a live one", encodeChain(t, liveB, liveD), encodeChain(t, expiredA, liveB), 0},
+		{"withdrawal replaces a live record", encodeChain(t, withdrawnA, liveB), encodeChain(t, liveA, liveB), 0},
+		{"withdrawal after pruning", encodeChain(t, withdrawnA), encodeChain(t, liveA, expiredB, expiredC), 0},
+		{"same chain put again", encodeChain(t, liveA, liveB), encodeChain(t, liveA, liveB), 0},
+		{"expired record put back", encodeChain(t, expiredA, liveB), encodeChain(t, liveB), 1},
+		{"live record dropped", encodeChain(t, liveD), encodeChain(t, liveB), 1},
+		{"live record dropped for a withdrawal", encodeChain(t, withdrawnA), encodeChain(t, liveA, liveB), 1},
+	}
+	v := OrcaValidator{}
+	for _, test := range tests {
+		got, err := v.Select(fuzzKey, [][]byte{test.incoming, test.stored})
+		if err != nil || got != test.want {
+			t.Errorf("%s: Select = %d, %v, want %d", test.name, got, err, test.want)
+		}
+	}
+}
+
+// Nodes registering at once leave partial chains on different peers, each holding a
+// record the others lack, whatever their sizes. Replaying what kad-dht does with them, a
+// lookup (processValues) must hand all of them to Merge, the fixup put of the lookup's
+// best value (updatePeerValues, then handlePutValue) must not overwrite another chain,
+// and once the merged chain is written back every peer holds every record.
+func TestConcurrentRegistrationsSurvive(t *testing.T) {
+	keys := make([]crypto.PrivKey, 5)
+	for i := range keys {
+		var err error
+		keys[i], _, err = crypto.GenerateEd25519Key(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := uint64(time.Now().Unix())
+	a, b, c, d, p := signedEntry(t, keys[0], now, 10, 0), signedEntry(t, keys[1], now, 10, 0), signedEntry(t, keys[2], now, 10, 0), signedEntry(t, keys[3], now, 10, 0), signedEntry(t, keys[4], now, 10, 0)
+
+	tests := []struct {
+		name   string
+		chains [][]record.Entry
+	}{
+		{"one record each", [][]record.Entry{{a}, {p}}},
+		{"larger chain found first", [][]record.Entry{{a, b, c, d}, {a, b, p}}},
+		{"smaller chain found first", [][]record.Entry{{a, b, p}, {a, b, c, d}}},
+		{"disjoint chains of different sizes", [][]record.Entry{{a, b, c}, {p}}},
+	}
+	v := OrcaValidator{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stored := make([][]byte, len(test.chains))
+			want := make([]string, 0)
+			for i := range test.chains {
+				stored[i] = encodeChain(t, test.chains[i]...)
+				for _, signature := range chainSignatures(t, stored[i]) {
+					if !slices.Contains(want, signature) {
+						want = append(want, signature)
+					}
+				}
+			}
+			slices.Sort(want)
+
+			//processValues hands on a received value only if Select prefers it to the best so far
+			var best []byte
+			found := make([][]byte, 0)
+			for _, value := range stored {
+				if best != nil {
+					i, err := v.Select(fuzzKey, [][]byte{best, value})
+					if err != nil {
+						t.Fatal(err)
+					}
+					if i != 1 {
+						continue
+					}
+				}
+				best = value
+				found = append(found, value)
+			}
+			merged, err := v.Merge(fuzzKey, found)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chainSignatures(t, merged); !slices.Equal(got, want) {
+				t.Fatalf("the lookup merged %d entries, want %d", len(got), len(want))
+			}
+
+			//handlePutValue keeps the stored record unless Select prefers the incoming one
+			put := func(peer int, incoming []byte) {
+				i, err := v.Select(fuzzKey, [][]byte{incoming, stored[peer]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if i == 0 {
+					stored[peer] = incoming
+				}
+			}
+			//updatePeerValues pushes the best value to the peers that did not return it
+			for peer := range stored {
+				put(peer, best)
+				if !slices.Equal(stored[peer], encodeChain(t, test.chains[peer]...)) && !slices.Equal(stored[peer], best) {
+					t.Fatalf("peer %d holds neither its chain nor the best one", peer)
+				}
+				if !containsAll(chainSignatures(t, stored[peer]), chainSignatures(t, encodeChain(t, test.chains[peer]...))) {
+					t.Fatalf("the fixup put dropped records of peer %d", peer)
+				}
+			}
+			for peer := range stored {
+				put(peer, merged)
+				if got := chainSignatures(t, stored[peer]); !slices.Equal(got, want) {
+					t.Errorf("peer %d holds %d entries after the merged chain was written back, want %d", peer, len(got), len(want))
+				}
+			}
+		})
+	}
+}
+
+// Whether every signature of want is in got.
+func containsAll(got []string, want []string) bool {
+	for _, signature := range want {
+		if !slices.Contains(got, signature) {
+			return false
+		}
+	}
+	return true
+}
+
+// The entries of an encoded chain.
+func mustDecode(t *testing.T, value []byte) []record.Entry {
+	t.Helper()
+	chain, err := record.Decode(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain.Entries
+}
+
+// Signs an entry of a chain with a new key, registered at the given time.
+type entrySigner func(key crypto.PrivKey, registeredAt uint64) record.Entry
+
+// Signs ratings of a producer.
+func ratingSigner(t *testing.T, producer peer.ID) entrySigner {
+	return func(key crypto.PrivKey, registeredAt uint64) record.Entry {
+		rater, err := util.MarshalUserID(key.GetPublic())
+		if err != nil {
+			t.Fatal(err)
+		}
+		message, err := proto.Marshal(&pb.Rating{Rater: rater, Score: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := record.Entry{RegisteredAt: registeredAt, TTL: 3600, Message: message}
+		entry.Signature, err = key.Sign(entry.SigningPayloadWithDomain(record.ReputationSignatureDomain, producer.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+}
+
+// Signs metadata of the file under fuzzKey.
+func metadataSigner(t *testing.T) entrySigner {
+	return func(key crypto.PrivKey, registeredAt uint64) record.Entry {
+		publisher, err := util.MarshalUserID(key.GetPublic())
+		if err != nil {
+			t.Fatal(err)
+		}
+		message, err := proto.Marshal(&pb.FileMetadata{Publisher: publisher, Name: "file.txt", Size: 1024, MimeType: "text/plain"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := record.Entry{RegisteredAt: registeredAt, TTL: 3600, Message: message}
+		entry.Signature, err = key.Sign(entry.SigningPayloadWithDomain(record.MetadataSignatureDomain, fuzzKey[len(MarketPrefix):]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+}
+
+// Signs index records of the keyword "music" listing the file under fuzzKey.
+func indexSigner(t *testing.T) entrySigner {
+	return func(key crypto.PrivKey, registeredAt uint64) record.Entry {
+		publisher, err := util.MarshalUserID(key.GetPublic())
+		if err != nil {
+			t.Fatal(err)
+		}
+		message, err := proto.Marshal(&pb.IndexEntry{Publisher: publisher, FileHashes: []string{fuzzKey[len(MarketPrefix):]}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := record.Entry{RegisteredAt: registeredAt, TTL: 3600, Message: message}
+		entry.Signature, err = key.Sign(entry.SigningPayloadWithDomain(record.IndexSignatureDomain, "music"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+}
+
+// Generates a key to sign an entry of a chain with.
+type keyMaker func(t *testing.T) crypto.PrivKey
+
+// Generates any new Ed25519 key.
+func anyKey(t *testing.T) crypto.PrivKey {
+	t.Helper()
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// Generates new Ed25519 keys whose holder records belong in a shard.
+func keyInShard(shard int) keyMaker {
+	return func(t *testing.T) crypto.PrivKey {
+		t.Helper()
+		for {
+			key := anyKey(t)
+			id, err := util.MarshalUserID(key.GetPublic())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Shard(id) == shard {
+				return key
+			}
+		}
+	}
+}
+
+// Signs holder records for fuzzKey.
+func holderSigner(t *testing.T) entrySigner {
+	return func(key crypto.PrivKey, registeredAt uint64) record.Entry {
+		return signedEntry(t, key, registeredAt, 10, 0)
+	}
+}
+
+// Entries signed by max+1 new keys, each registered a second before the last.
+func cappedEntries(t *testing.T, sign entrySigner, max int, newKey keyMaker) []record.Entry {
+	t.Helper()
+	now := uint64(time.Now().Unix())
+	entries := make([]record.Entry, max+1)
+	for i := range entries {
+		entries[i] = sign(newKey(t), now-uint64(i))
+	}
+	return entries
+}
+
+// Chains with a cap on their entries reject a value over it, and merging values that hold
+// more keeps only the newest entries, which is a value they accept.
+func TestChainCaps(t *testing.T) {
+	producer, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	producerID, err := peer.IDFromPrivateKey(producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		validator interface {
+			Validate(string, []byte) error
+			Merge(string, [][]byte) ([]byte, error)
+		}
+		max    int
+		sign   entrySigner
+		newKey keyMaker
+	}{
+		{"reputation", ReputationPrefix + producerID.String(), ReputationValidator{}, record.MaxRaters, ratingSigner(t, producerID), anyKey},
+		{"metadata", MetadataPrefix + fuzzKey[len(MarketPrefix):], MetadataValidator{}, record.MaxMetadataPublishers, metadataSigner(t), anyKey},
+		{"holder shard", fuzzKey + "/0", OrcaValidator{}, record.MaxShardEntries, holderSigner(t), keyInShard(0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := cappedEntries(t, test.sign, test.max, test.newKey)
+
+			if err := test.validator.Validate(test.key, encodeChain(t, entries...)); err == nil {
+				t.Errorf("a chain of %d entries was accepted", len(entries))
+			}
+			if err := test.validator.Validate(test.key, encodeChain(t, entries[:test.max]...)); err != nil {
+				t.Errorf("a chain of %d entries was rejected: %v", test.max, err)
+			}
+
+			merged, err := test.validator.Merge(test.key, [][]byte{
+				encodeChain(t, entries[:test.max]...),
+				encodeChain(t, entries[test.max]),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.validator.Validate(test.key, merged); err != nil {
+				t.Errorf("the merged chain was rejected: %v", err)
+			}
+			want := chainSignatures(t, encodeChain(t, entries[:test.max]...))
+			if got := chainSignatures(t, merged); !slices.Equal(got, want) {
+				t.Errorf("the merged chain did not keep the %d newest entries", test.max)
+			}
+		})
+	}
+}
+
+// Once a capped chain is full, a chain holding a new entry in place of the oldest, as Merge
+// leaves it, wins over the full chain both when it is put and when a lookup finds it.
+func TestSelectAtCap(t *testing.T) {
+	producer, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	producerID, err := peer.IDFromPrivateKey(producer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		validator Selector
+		max       int
+		sign      entrySigner
+		newKey    keyMaker
+	}{
+		{"reputation", ReputationPrefix + producerID.String(), ReputationValidator{}, record.MaxRaters, ratingSigner(t, producerID), anyKey},
+		{"metadata", MetadataPrefix + fuzzKey[len(MarketPrefix):], MetadataValidator{}, record.MaxMetadataPublishers, metadataSigner(t), anyKey},
+		{"index", IndexPrefix + "music", IndexValidator{}, record.MaxIndexPublishers, indexSigner(t), anyKey},
+		{"holder shard", fuzzKey + "/0", OrcaValidator{}, record.MaxShardEntries, holderSigner(t), keyInShard(0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//the newest entry is first, the oldest last
+			entries := cappedEntries(t, test.sign, test.max, test.newKey)
+			full := encodeChain(t, entries[1:]...)
+			trimmed := encodeChain(t, entries[:test.max]...)
+
+			if got, err := test.validator.Select(test.key, [][]byte{trimmed, full}); err != nil || got != 0 {
+				t.Errorf("putting the trimmed chain over the full one: Select = %d, %v, want 0", got, err)
+			}
+			if got, err := test.validator.Select(test.key, [][]byte{full, trimmed}); err != nil || got != 1 {
+				t.Errorf("finding the trimmed chain after the full one: Select = %d, %v, want 1", got, err)
+			}
+		})
+	}
+}
+
+// The chain under orcanet/market/<hash> may hold at most record.ShardThreshold live
+// records, however many expired ones it still carries.
+func TestChainLiveLimit(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	live := cappedEntries(t, holderSigner(t), record.ShardThreshold, anyKey)
+	expired := signedEntry(t, anyKey(t), now-7200, 10, 0)
+
+	v := OrcaValidator{}
+	if err := v.Validate(fuzzKey, encodeChain(t, live...)); err == nil {
+		t.Errorf("a chain of %d live records was accepted", len(live))
+	}
+	if err := v.Validate(fuzzKey, encodeChain(t, append(live[1:], expired)...)); err != nil {
+		t.Errorf("a chain of %d live records and an expired one was rejected: %v", record.ShardThreshold, err)
+	}
+}